@@ -18,27 +18,35 @@ const (
 
 var cache map[key]*model.Blockchain = make(map[key]*model.Blockchain)
 
-func getBlockchain(c *fiber.Ctx) *model.Blockchain {
+// initBlockchain creates the node's blockchain once at startup.
+// port is the port this node listens on, which is used to find its neighbors.
+// minersWallet receives the rewards and signs the blocks.
+// clock is the time of the node, which engine must use too.
+func initBlockchain(port int, minersWallet *model.Wallet, engine model.Consensus, clock *model.NetworkClock, params *model.ChainParams) *model.Blockchain {
 	bc, ok := cache[cacheKey]
 	if !ok {
-		minersWallet := model.NewWallet()
-		port, _ := strconv.Atoi(c.Port())
-		bc = model.NewBlockchain(minersWallet.BlockchainAddress(), port)
+		bc = model.NewBlockchain(minersWallet.BlockchainAddress(), port, engine, clock, params)
 		cache[cacheKey] = bc
-		log.Printf("private_key %v", minersWallet.PrivateKeyStr())
 		log.Printf("public_key %v", minersWallet.PublicKeyStr())
 		log.Printf("blockchain_address %v", minersWallet.BlockchainAddress())
+		log.Printf("consensus %v", engine.Name())
+		log.Printf("network %v chain_id %v genesis %v", params.Network().Name, params.ChainID, bc.GenesisHash())
+		go bc.Run()
 	}
 	return bc
 }
 
+func getBlockchain() *model.Blockchain {
+	return cache[cacheKey]
+}
+
 func getChainHandler(c *fiber.Ctx) error {
-	bc := getBlockchain(c)
-	return c.JSON(bc)
+	bc := getBlockchain()
+	return c.JSON(model.ChainResponse{Chain: bc.Blocks(), BlockchainAddress: bc.BlockchainAddress})
 }
 
 func getTransactions(c *fiber.Ctx) error {
-	bc := getBlockchain(c)
+	bc := getBlockchain()
 	transactions := bc.TransactionPool()
 	return c.JSON(model.BlockchainTransactionResponse{
 		Transactions: transactions,
@@ -49,20 +57,101 @@ func getTransactions(c *fiber.Ctx) error {
 func createTransactions(c *fiber.Ctx) error {
 	var t model.BlockchainTransactionRequest
 	if err := c.BodyParser(&t); err != nil {
+		model.CountRejectedTransaction(model.REJECT_REASON_MALFORMED)
 		return c.Status(http.StatusBadRequest).JSON(err)
 	}
-	publicKey := common.PublicKeyFromString(t.SenderPublicKey)
-	signature := common.SignatureFromString(t.Signature)
-	bc := getBlockchain(c)
-	isCreated := bc.CreateTransaction(t.SenderBlockchainAddress, t.RecipientBlockchainAddress, t.Value, publicKey, signature)
-	if !isCreated {
-		return c.SendStatus(fiber.StatusInternalServerError)
+	if err := t.Validate(); err != nil {
+		model.CountRejectedTransaction(model.REJECT_REASON_MALFORMED)
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	scheme, err := common.SchemeOfTransaction(t.SignatureScheme, t.SenderBlockchainAddress)
+	if err != nil {
+		model.CountRejectedTransaction(model.REJECT_REASON_MALFORMED)
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	publicKey, err := scheme.ParsePublicKey(t.SenderPublicKey)
+	if err != nil {
+		model.CountRejectedTransaction(model.REJECT_REASON_MALFORMED)
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	// the signature only proves the key, so the key must also be the sender's
+	if scheme.Address(publicKey) != t.SenderBlockchainAddress {
+		model.CountRejectedTransaction(model.REJECT_REASON_INVALID_SIGNATURE)
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse("sender_public_key does not match sender_blockchain_address"))
+	}
+	signature, err := scheme.ParseSignature(t.Signature)
+	if err != nil {
+		model.CountRejectedTransaction(model.REJECT_REASON_MALFORMED)
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	s := &model.TransactionSignature{Scheme: scheme, PublicKey: publicKey, Signature: signature}
+	bc := getBlockchain()
+	var created *model.Transaction
+	switch {
+	case t.Type != "":
+		created, err = bc.CreateStakeTransaction(t.SenderBlockchainAddress, t.Type, t.Value, t.Nonce, s)
+	case len(t.Outputs) > 0:
+		created, err = bc.CreateBatchTransaction(t.SenderBlockchainAddress, t.Outputs, t.Nonce, s)
+	default:
+		created, err = bc.CreateTransaction(t.SenderBlockchainAddress, t.RecipientBlockchainAddress, t.Value, t.Nonce, s)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	return c.Status(fiber.StatusCreated).JSON(model.TransactionCreatedResponse{ID: created.ID()})
+}
+
+// getTransaction finds a transaction by the ID returned from POST /transactions.
+func getTransaction(c *fiber.Ctx) error {
+	t, height, ok := getBlockchain().FindTransaction(c.Params("id"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(common.NewResponse("transaction not found"))
+	}
+	resp := model.TransactionDetailResponse{
+		ID:          t.ID(),
+		Status:      model.TRANSACTION_STATUS_PENDING,
+		Transaction: t,
+	}
+	if height >= 0 {
+		resp.Status = model.TRANSACTION_STATUS_CONFIRMED
+		resp.BlockHeight = &height
+	}
+	return c.JSON(resp)
+}
+
+// verifyMessage checks that a message was signed by the key of the address.
+// An invalid signature is a valid request, so it is 200 with valid=false.
+func verifyMessage(c *fiber.Ctx) error {
+	var r model.MessageVerifyRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	scheme, err := common.SchemeOfTransaction(r.SignatureScheme, r.BlockchainAddress)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
 	}
-	return c.SendStatus(fiber.StatusCreated)
+	publicKey, err := scheme.ParsePublicKey(r.PublicKey)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	signature, err := scheme.ParseSignature(r.Signature)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	if scheme.Address(publicKey) != r.BlockchainAddress {
+		return c.JSON(model.MessageVerifyResponse{Reason: "public_key does not match blockchain_address"})
+	}
+	if !common.VerifyMessage(scheme, publicKey, r.Message, signature) {
+		return c.JSON(model.MessageVerifyResponse{Reason: "signature does not match the message"})
+	}
+	return c.JSON(model.MessageVerifyResponse{Valid: true})
 }
 
 func mine(c *fiber.Ctx) error {
-	bc := getBlockchain(c)
+	bc := getBlockchain()
 	isMined := bc.Mining()
 	if !isMined {
 		return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse("couldn't mine"))
@@ -71,16 +160,65 @@ func mine(c *fiber.Ctx) error {
 }
 
 func startMine(c *fiber.Ctx) error {
-	bc := getBlockchain(c)
+	bc := getBlockchain()
 	bc.StartMining()
 	return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse("auto mining start"))
 }
 
 func amount(c *fiber.Ctx) error {
-	bc := getBlockchain(c)
+	bc := getBlockchain()
+	bcAddress := c.Query("blockchain_address")
+	if err := common.ValidateAddress(bcAddress); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	return c.JSON(bc.Amount(bcAddress))
+}
+
+// nonce returns the nonce to sign the next transaction of the address with.
+func nonce(c *fiber.Ctx) error {
 	bcAddress := c.Query("blockchain_address")
-	amount := bc.CalculateTotalAmount(bcAddress)
-	return c.JSON(model.AmountResponse{
-		Amount: amount,
+	if err := common.ValidateAddress(bcAddress); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	return c.JSON(model.NonceResponse{
+		BlockchainAddress: bcAddress,
+		Nonce:             getBlockchain().NextNonce(bcAddress),
+	})
+}
+
+func getSupply(c *fiber.Ctx) error {
+	return c.JSON(getBlockchain().Supply())
+}
+
+// getBlock returns the block at the height given in the path. "latest" is the last block.
+func getBlock(c *fiber.Ctx) error {
+	bc := getBlockchain()
+	chain := bc.Blocks()
+	height := len(chain) - 1
+	if p := c.Params("height"); p != "latest" {
+		h, err := strconv.Atoi(p)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse("height must be a number or latest"))
+		}
+		height = h
+	}
+	if height < 0 || height >= len(chain) {
+		return c.Status(fiber.StatusNotFound).JSON(common.NewResponse("block not found"))
+	}
+	b := chain[height]
+	return c.JSON(model.BlockResponse{
+		Height:          height,
+		Hash:            b.Hash(),
+		ProducerAddress: b.ProducerAddress(),
+		Block:           b,
+	})
+}
+
+func getNeighbors(c *fiber.Ctx) error {
+	bc := getBlockchain()
+	neighbors := bc.Neighbors()
+	return c.JSON(model.NeighborsResponse{
+		Neighbors: neighbors,
+		Length:    len(neighbors),
 	})
 }
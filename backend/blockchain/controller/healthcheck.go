@@ -1,11 +1,61 @@
 package controller
 
 import (
-	"fmt"
-
 	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/blockchain/model"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+const (
+	statusOK      = "ok"
+	statusSyncing = "syncing"
+	// chain is only kept in memory for now.
+	storageInMemory = "in-memory"
 )
 
+func health() model.HealthResponse {
+	bc := getBlockchain()
+	chain := bc.Blocks()
+	status := statusOK
+	if bc.Syncing() {
+		status = statusSyncing
+	}
+	return model.HealthResponse{
+		Status:            status,
+		NodeAddress:       bc.Address(),
+		BlockchainAddress: bc.BlockchainAddress,
+		ChainHeight:       len(chain),
+		TipHash:           chain[len(chain)-1].Hash(),
+		PoolSize:          len(bc.TransactionPool()),
+		Mining:            bc.IsMining(),
+		Peers:             len(bc.Neighbors()),
+		Storage:           storageInMemory,
+		Consensus:         bc.Consensus().Name(),
+		TimeOffsetMs:      bc.Clock().Offset().Milliseconds(),
+		Network:           bc.Params().Network().Name,
+		ChainID:           bc.Params().ChainID,
+		GenesisHash:       bc.GenesisHash(),
+	}
+}
+
 func healthCheck(c *fiber.Ctx) error {
-	return c.SendString(fmt.Sprintf("Hello! You've requested: %s", c.Path()))
+	return c.JSON(health())
+}
+
+// readiness fails while the node is still catching up with its neighbors,
+// so that load balancers can route around it.
+func readiness(c *fiber.Ctx) error {
+	h := health()
+	if h.Status != statusOK {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(h)
+	}
+	return c.JSON(h)
+}
+
+func consensus(c *fiber.Ctx) error {
+	bc := getBlockchain()
+	if !bc.ResolveConflicts() {
+		return c.JSON(common.NewResponse("chain is not replaced"))
+	}
+	return c.JSON(common.NewResponse("chain is replaced"))
 }
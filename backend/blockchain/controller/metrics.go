@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/yagikota/blockchain_with_go/backend/blockchain/model"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+var httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "http_request_duration_seconds",
+	Help:    "HTTP request latency by route.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"method", "route", "status"})
+
+// The gauges are read from the blockchain at scrape time, through the accessors that take its lock.
+// They are registered once with the package, so the router can be built more than once.
+var (
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "blockchain_chain_height",
+		Help: "Number of blocks in the chain.",
+	}, func() float64 {
+		return gaugeOf(func(bc *model.Blockchain) int { return len(bc.Blocks()) })
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "blockchain_transaction_pool_size",
+		Help: "Number of transactions waiting to be mined.",
+	}, func() float64 {
+		return gaugeOf(func(bc *model.Blockchain) int { return len(bc.TransactionPool()) })
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "blockchain_peers",
+		Help: "Number of neighbor nodes.",
+	}, func() float64 {
+		return gaugeOf(func(bc *model.Blockchain) int { return len(bc.Neighbors()) })
+	})
+)
+
+func gaugeOf(f func(bc *model.Blockchain) int) float64 {
+	bc := getBlockchain()
+	if bc == nil {
+		return 0
+	}
+	return float64(f(bc))
+}
+
+func metricsMiddleware(c *fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+	status := c.Response().StatusCode()
+	if e, ok := err.(*fiber.Error); ok {
+		status = e.Code
+	}
+	httpRequestDuration.WithLabelValues(c.Method(), c.Route().Path, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	return err
+}
+
+func metrics(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, common.MetricsContentType)
+	return common.WriteMetrics(c)
+}
@@ -2,18 +2,36 @@ package controller
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/blockchain/model"
 )
 
-func InitRouter() *fiber.App {
+func InitRouter(port int, minersWallet *model.Wallet, engine model.Consensus, clock *model.NetworkClock, params *model.ChainParams) *fiber.App {
+	initBlockchain(port, minersWallet, engine, clock, params)
+
 	app := fiber.New()
+	app.Use(metricsMiddleware)
+	app.Get("/metrics", metrics)
 	v1 := app.Group("/v1")
 	v1.Get("/health_check", healthCheck)
+	v1.Get("/ready", readiness)
 	v1.Get("/chain", getChainHandler)
+	v1.Get("/blocks/:height", getBlock)
+	v1.Get("/neighbors", getNeighbors)
 	v1.Get("/transactions", getTransactions)
 	v1.Post("/transactions", createTransactions)
+	v1.Get("/transactions/:id", getTransaction)
+	v1.Post("/messages/verify", verifyMessage)
 	v1.Get("/mine", mine)
 	v1.Get("/mine/start", startMine)
 	v1.Get("/amount", amount)
+	v1.Get("/nonce", nonce)
+	v1.Get("/supply", getSupply)
+	v1.Put("/consensus", consensus)
+	v1.Get("/validators", getValidators)
+	v1.Post("/validators/votes", voteValidator)
+	v1.Delete("/validators/votes/:public_key", discardValidatorVote)
+	v1.Get("/stakes", getStakes)
+	v1.Post("/evidence", submitEvidence)
 
 	return app
 }
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/blockchain/model"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+// getStakes returns the stakes at the tip of the chain.
+// With ?blockchain_address= it returns only the stake of the address.
+func getStakes(c *fiber.Ctx) error {
+	bc := getBlockchain()
+	pos, ok := bc.Consensus().(*model.ProofOfStake)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(model.ErrStakingDisabled.Error()))
+	}
+	stakes := pos.Stakes(bc.Blocks())
+	if address := c.Query("blockchain_address"); address != "" {
+		filtered := []*model.StakeResponse{}
+		for _, s := range stakes {
+			if s.BlockchainAddress == address {
+				filtered = append(filtered, s)
+			}
+		}
+		stakes = filtered
+	}
+	return c.JSON(model.StakesResponse{
+		Stakes: stakes,
+		Length: len(stakes),
+	})
+}
+
+// submitEvidence puts a slash transaction into the pool. Anyone who saw both blocks can submit it.
+func submitEvidence(c *fiber.Ctx) error {
+	var e model.SlashEvidence
+	if err := c.BodyParser(&e); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	t, err := getBlockchain().SubmitEvidence(&e)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	return c.Status(fiber.StatusCreated).JSON(model.TransactionCreatedResponse{ID: t.ID()})
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/blockchain/model"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+// getAuthority returns the poa engine of the node.
+// The other consensus has no validators, so it writes 400 and returns nil.
+func getAuthority(c *fiber.Ctx) (*model.ProofOfAuthority, error) {
+	poa, ok := getBlockchain().Consensus().(*model.ProofOfAuthority)
+	if !ok {
+		msg := fmt.Sprintf("consensus %s has no validators", getBlockchain().Consensus().Name())
+		return nil, c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(msg))
+	}
+	return poa, nil
+}
+
+func validatorsResponse(poa *model.ProofOfAuthority) model.ValidatorsResponse {
+	validators := poa.Validators(getBlockchain().Blocks())
+	i := sort.SearchStrings(validators, poa.PublicKey())
+	return model.ValidatorsResponse{
+		Validators: validators,
+		Length:     len(validators),
+		Self:       poa.PublicKey(),
+		Validator:  i < len(validators) && validators[i] == poa.PublicKey(),
+		Proposals:  poa.Proposals(),
+	}
+}
+
+func getValidators(c *fiber.Ctx) error {
+	poa, err := getAuthority(c)
+	if poa == nil {
+		return err
+	}
+	return c.JSON(validatorsResponse(poa))
+}
+
+// voteValidator makes this node vote in the blocks it produces until the vote passes or is discarded.
+// Only the holder of the key of the node can change its votes, see ProofOfAuthority.VerifyRequest.
+func voteValidator(c *fiber.Ctx) error {
+	poa, err := getAuthority(c)
+	if poa == nil {
+		return err
+	}
+	var r model.ValidatorVoteRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	message := common.ValidatorVoteMessage(r.PublicKey, r.Authorize, r.Timestamp)
+	if err := poa.VerifyRequest(message, r.Timestamp, r.Signature); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(common.NewResponse(err.Error()))
+	}
+	if err := poa.Propose(getBlockchain().Blocks(), r.PublicKey, r.Authorize); err != nil {
+		status := fiber.StatusBadRequest
+		if errors.Is(err, model.ErrNotValidator) {
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(common.NewResponse(err.Error()))
+	}
+	return c.JSON(validatorsResponse(poa))
+}
+
+// discardValidatorVote takes the timestamp and the signature of common.ValidatorDiscardMessage in the query.
+func discardValidatorVote(c *fiber.Ctx) error {
+	poa, err := getAuthority(c)
+	if poa == nil {
+		return err
+	}
+	publicKey := c.Params("public_key")
+	timestamp, err := strconv.ParseInt(c.Query("timestamp"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse("timestamp must be UNIX time in ns"))
+	}
+	message := common.ValidatorDiscardMessage(publicKey, timestamp)
+	if err := poa.VerifyRequest(message, timestamp, c.Query("signature")); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(common.NewResponse(err.Error()))
+	}
+	poa.Discard(publicKey)
+	return c.JSON(validatorsResponse(poa))
+}
@@ -5,16 +5,81 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/yagikota/blockchain_with_go/backend/blockchain/controller"
+	"github.com/yagikota/blockchain_with_go/backend/blockchain/model"
+	"github.com/yagikota/blockchain_with_go/backend/common"
 )
 
+// ENV_PRIVATE_KEY is the hex private key of the node. The key is never a flag,
+// so that it doesn't show up in the shell history or the process list.
+const ENV_PRIVATE_KEY = "BLOCKCHAIN_PRIVATE_KEY"
+
 // https://docs.gofiber.io/api/app#group
 func main() {
-	app1 := controller.InitRouter()
-	port := flag.Int("port", 8001, "TCP Port Number of Blockchain Server")
+	networkName := flag.String("network", common.NETWORK_MAIN, "network of the node (main, test, regtest)")
+	port := flag.Int("port", 0, "TCP Port Number of Blockchain Server. The first node port of the network if 0")
+	consensusName := flag.String("consensus", model.CONSENSUS_POW, "consensus algorithm of the chain (pow, poa, pos)")
+	keyScheme := flag.String("key-scheme", common.SCHEME_ECDSA_P256, "signature scheme of the key of this node (ecdsa-p256, ecdsa-secp256k1, ed25519)")
+	privateKeyFile := flag.String("private-key-file", "", "file of the hex private key of this node in -key-scheme (env "+ENV_PRIVATE_KEY+" for the key itself). A new key is generated if neither is set")
+	validators := flag.String("validators", "", "comma separated public keys of the genesis validators (poa). The stakers of pos are the genesis_stakes of the chain params")
+	genesis := flag.String("genesis", "", "path to the chain params file (JSON). The default params of the network are used if empty")
 	flag.Parse()
+
+	network, err := common.NetworkByName(*networkName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *port == 0 {
+		*port = network.NodePortStart
+	}
+	// the address version must be set before the wallet creates its address
+	params, err := model.LoadChainParams(network, *genesis)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scheme, err := common.SchemeByID(*keyScheme)
+	if err != nil {
+		log.Fatal(err)
+	}
+	privateKey, err := readPrivateKey(*privateKeyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var minersWallet *model.Wallet
+	if privateKey != "" {
+		minersWallet, err = model.NewWalletFromPrivateKey(scheme, privateKey)
+	} else {
+		minersWallet, err = model.NewWallet(scheme)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	clock := model.NewNetworkClock(model.SystemClock)
+	cfg := &model.ConsensusConfig{PrivateKey: minersWallet.PrivateKey(), Clock: clock, Difficulty: params.Difficulty, GenesisStakes: params.GenesisStakes}
+	if *validators != "" {
+		cfg.Validators = strings.Split(*validators, ",")
+	}
+	engine, err := model.NewConsensus(*consensusName, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	app1 := controller.InitRouter(*port, minersWallet, engine, clock, params)
 	fmt.Println(*port)
 	log.Fatal(app1.Listen(net.JoinHostPort("localhost", strconv.Itoa(*port))))
 }
+
+// readPrivateKey reads the key of the node from path, or from ENV_PRIVATE_KEY when path is empty.
+func readPrivateKey(path string) (string, error) {
+	if path == "" {
+		return os.Getenv(ENV_PRIVATE_KEY), nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
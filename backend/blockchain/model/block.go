@@ -1,11 +1,16 @@
 package model
 
 import (
-	"crypto/ecdsa"
+	"crypto"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,42 +20,111 @@ import (
 )
 
 const (
-	MINING_DIFFICULTY = 3 // difficulty of mining.
-	MINING_SENDER     = "THE BLOCKCHAIN"
-	MINING_REWARD     = 1.0
-	MINING_TIME_SEC   = 20
+	MINING_SENDER   = "THE BLOCKCHAIN"
+	MINING_REWARD   = 1.0
+	MINING_TIME_SEC = 20
+
+	NEIGHBOR_HOST           = "127.0.0.1" // FindNeighbors only understands IPv4 addresses.
+	NEIGHBOR_IP_RANGE_START = 0
+	NEIGHBOR_IP_RANGE_END   = 1
+)
+
+var (
+	ErrInvalidSignature    = errors.New("invalid signature")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrInvalidTimestamp    = errors.New("invalid timestamp")
 )
 
+// The hash of a block is the hash of its header (see BlockHeader). The header covers the
+// transactions through MerkleRoot, and the producer signs the hash.
+// Every block but the genesis block is signed by its producer, whatever the consensus.
+// The reward of the block goes to the address of the producer.
+// Difficulty is set only by pow, Vote only by poa, and Height and Seed only by pos.
 type Block struct {
 	Timestamp    int64          `json:"timestamp"`
 	Nonce        int            `json:"nonce"`
 	PreviousHash string         `json:"previous_hash"`
+	MerkleRoot   string         `json:"merkle_root"`
+	Difficulty   int            `json:"difficulty"`
 	Transactions []*Transaction `json:"transactions"`
+	Producer     string         `json:"producer,omitempty"`  // public key of the producer
+	Signature    string         `json:"signature,omitempty"` // signature of the producer over the hash
+	Vote         *ValidatorVote `json:"vote,omitempty"`
+	Height       int            `json:"height,omitempty"` // signed, so that two blocks at the same height prove a double sign
+	Seed         string         `json:"seed,omitempty"`   // signature of the producer over the seed of the parent and the slot, see ProofOfStake
 }
 
 func (b *Block) Print() {
 	fmt.Printf("timestamp     %d\n", b.Timestamp)
 	fmt.Printf("nonce         %d\n", b.Nonce)
-	fmt.Printf("previous_hash %x\n", b.PreviousHash)
+	fmt.Printf("previous_hash %s\n", b.PreviousHash)
+	fmt.Printf("merkle_root   %s\n", b.MerkleRoot)
+	fmt.Printf("difficulty    %d\n", b.Difficulty)
 	for _, t := range b.Transactions {
 		t.Print()
 	}
 }
 
-func NewBlock(nonce int, previousHash string, transactions []*Transaction) *Block {
+// NewBlock creates a block at the current time of clock.
+func NewBlock(nonce int, previousHash string, transactions []*Transaction, clock Clock) *Block {
 	return &Block{
-		Timestamp:    time.Now().UnixNano(),
+		Timestamp:    clock.Now().UnixNano(),
 		Nonce:        nonce,
 		PreviousHash: previousHash,
+		MerkleRoot:   MerkleRoot(transactions),
 		Transactions: transactions,
 	}
 }
 
-// TODO: hashの方法調べる
+// BlockHeader is the part of a block that its hash covers, and the proof of work is computed on.
+// Every field but Signature and Transactions is in it, so two blocks with the same hash are the same block.
+// The fields of the consensus are omitted when empty, so that the hash of the genesis block stays the same.
+type BlockHeader struct {
+	Timestamp    int64          `json:"timestamp"`
+	PreviousHash string         `json:"previous_hash"`
+	MerkleRoot   string         `json:"merkle_root"`
+	Difficulty   int            `json:"difficulty"`
+	Nonce        int            `json:"nonce"`
+	Producer     string         `json:"producer,omitempty"`
+	Vote         *ValidatorVote `json:"vote,omitempty"`
+	Height       int            `json:"height,omitempty"`
+	Seed         string         `json:"seed,omitempty"`
+}
+
+func (b *Block) Header() *BlockHeader {
+	return &BlockHeader{
+		Timestamp:    b.Timestamp,
+		PreviousHash: b.PreviousHash,
+		MerkleRoot:   b.MerkleRoot,
+		Difficulty:   b.Difficulty,
+		Nonce:        b.Nonce,
+		Producer:     b.Producer,
+		Vote:         b.Vote,
+		Height:       b.Height,
+		Seed:         b.Seed,
+	}
+}
+
+// Hash is the hash of the header, so it can be recomputed from the stored block alone.
+// hex文字列で返す。生のbyte列だとJSONに載せたときに壊れて、他のnodeでchainを検証できない。
 func (b *Block) Hash() string {
-	m, _ := json.Marshal(b)
+	return fmt.Sprintf("%x", b.SealHash())
+}
+
+// ProducerAddress is the blockchain address of Producer, or "" if the block is not signed.
+func (b *Block) ProducerAddress() string {
+	scheme, pub, err := common.ParseAnyPublicKey(b.Producer)
+	if err != nil {
+		return ""
+	}
+	return scheme.Address(pub)
+}
+
+// SealHash is what the producer signs: the hash of the header as bytes.
+func (b *Block) SealHash() []byte {
+	m, _ := json.Marshal(b.Header())
 	h := sha256.Sum256(m)
-	return string(h[:])
+	return h[:]
 }
 
 type Blockchain struct {
@@ -59,19 +133,135 @@ type Blockchain struct {
 	BlockchainAddress string   // Use bitcoin address as blockchainAddress.
 	port              int
 	mux               sync.Mutex
+
+	neighbors    []string
+	muxNeighbors sync.Mutex
+
+	syncing bool
+	mining  bool
+
+	consensus   Consensus
+	clock       *NetworkClock
+	params      *ChainParams
+	genesisHash string
 }
 
-func NewBlockchain(blockchainAddress string, port int) *Blockchain {
-	b := new(Block)
+// NewBlockchain creates the chain of a node from the genesis block of params.
+// clock should be the one the consensus uses, so that the node agrees with itself about the time.
+func NewBlockchain(blockchainAddress string, port int, consensus Consensus, clock *NetworkClock, params *ChainParams) *Blockchain {
 	bc := new(Blockchain)
-	bc.CreateBlock(0, b.Hash())
+	bc.clock = clock
+	bc.params = params
+	bc.appendBlock(params.GenesisBlock())
+	bc.genesisHash = bc.Chain[0].Hash()
 	bc.BlockchainAddress = blockchainAddress
 	bc.port = port
+	bc.consensus = consensus
+	bc.syncing = true
 	return bc
 }
 
+// Run finds the neighbors and catches up with the longest chain among them.
+// The node reports itself as syncing until the first ResolveConflicts finishes.
+func (bc *Blockchain) Run() {
+	bc.ResolveConflicts()
+	bc.mux.Lock()
+	bc.syncing = false
+	bc.mux.Unlock()
+}
+
+func (bc *Blockchain) Address() string {
+	return net.JoinHostPort(NEIGHBOR_HOST, strconv.Itoa(bc.port))
+}
+
+func (bc *Blockchain) Syncing() bool {
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	return bc.syncing
+}
+
+func (bc *Blockchain) Consensus() Consensus {
+	return bc.consensus
+}
+
+func (bc *Blockchain) Clock() *NetworkClock {
+	return bc.clock
+}
+
+func (bc *Blockchain) Params() *ChainParams {
+	return bc.params
+}
+
+func (bc *Blockchain) GenesisHash() string {
+	return bc.genesisHash
+}
+
+func (bc *Blockchain) IsMining() bool {
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	return bc.mining
+}
+
+// SetNeighbors scans the node ports of the network for other blockchain nodes of the same network.
+// The scan dials every candidate, so it runs without holding muxNeighbors.
+// There is no background scan: the node scans when it needs its neighbors,
+// before it resolves conflicts and before it announces a new block.
+func (bc *Blockchain) SetNeighbors() {
+	network := bc.params.Network()
+	found := common.FindNeighbors(
+		NEIGHBOR_HOST, bc.port,
+		NEIGHBOR_IP_RANGE_START, NEIGHBOR_IP_RANGE_END,
+		network.NodePortStart, network.NodePortEnd)
+	neighbors := make([]string, 0, len(found))
+	for _, n := range found {
+		if err := bc.checkGenesis(n); err != nil {
+			log.Printf("ERROR: neighbor %s: %v", n, err)
+			continue
+		}
+		neighbors = append(neighbors, n)
+	}
+	bc.muxNeighbors.Lock()
+	bc.neighbors = neighbors
+	bc.muxNeighbors.Unlock()
+	log.Printf("neighbors %v", neighbors)
+}
+
+// checkGenesis asks a neighbor for its genesis hash. A node of another network is not a neighbor.
+func (bc *Blockchain) checkGenesis(neighbor string) error {
+	resp, err := http.Get(fmt.Sprintf("http://%s/v1/health_check", neighbor))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var h HealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+		return err
+	}
+	if h.GenesisHash != bc.genesisHash {
+		return fmt.Errorf("%w: chain %q with genesis %s", ErrGenesisMismatch, h.ChainID, h.GenesisHash)
+	}
+	return nil
+}
+
+func (bc *Blockchain) Neighbors() []string {
+	bc.muxNeighbors.Lock()
+	defer bc.muxNeighbors.Unlock()
+	return bc.neighbors
+}
+
+// TransactionPool returns a copy of the pool, which the caller can read without the lock.
 func (bc *Blockchain) TransactionPool() []*Transaction {
-	return bc.transactionPool
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	return append([]*Transaction(nil), bc.transactionPool...)
+}
+
+// Blocks returns the current chain. Blocks are only appended or the chain is replaced as a whole,
+// so the returned slice stays valid without the lock.
+func (bc *Blockchain) Blocks() []*Block {
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	return bc.Chain
 }
 
 func (bc *Blockchain) Print() {
@@ -82,133 +272,482 @@ func (bc *Blockchain) Print() {
 	fmt.Printf("%s\n", strings.Repeat("*", 25))
 }
 
-// TODO: function name maybe incorrect.
-func (bc *Blockchain) CreateBlock(nonce int, previousHash string) {
-	b := NewBlock(nonce, previousHash, bc.transactionPool)
+func (bc *Blockchain) appendBlock(b *Block) {
+	if len(bc.Chain) > 0 {
+		interval := time.Duration(b.Timestamp - bc.LastBlock().Timestamp)
+		blockInterval.Observe(interval.Seconds())
+	}
 	bc.Chain = append(bc.Chain, b)
-	bc.transactionPool = []*Transaction{}
+	bc.removeFromPool(b.Transactions)
 }
 
-func (bc *Blockchain) CreateTransaction(sender, recipient string, value float64, senderPublicKey *ecdsa.PublicKey, s *common.Signature) bool {
-	isTransacted := bc.AddTransaction(sender, recipient, value, senderPublicKey, s)
-	// TODO: Sync
-	return isTransacted
+// removeFromPool drops the transactions of a block from the pool.
+// Transactions that arrived while the block was sealed stay for the next block.
+func (bc *Blockchain) removeFromPool(included []*Transaction) {
+	ids := make(map[string]bool, len(included))
+	for _, t := range included {
+		ids[t.ID()] = true
+	}
+	pool := make([]*Transaction, 0, len(bc.transactionPool))
+	for _, t := range bc.transactionPool {
+		if !ids[t.ID()] {
+			pool = append(pool, t)
+		}
+	}
+	bc.transactionPool = pool
 }
 
-// AddTransaction add a transaction to pool.
-func (bc *Blockchain) AddTransaction(sender, recipient string, value float64, senderPublicKey *ecdsa.PublicKey, s *common.Signature) bool {
+// CreateTransaction adds a payment to the pool if the sender can spend value.
+func (bc *Blockchain) CreateTransaction(sender, recipient string, value float64, nonce uint64, s *TransactionSignature) (*Transaction, error) {
 	t := NewTransaction(sender, recipient, value)
-	// マイニングの場合、送り手はBCになる。
-	if sender == MINING_SENDER {
-		bc.transactionPool = append(bc.transactionPool, t)
-		return true
+	t.Nonce = nonce
+	if !bc.VerifyTransactionSignature(s, t) {
+		CountRejectedTransaction(REJECT_REASON_INVALID_SIGNATURE)
+		return nil, ErrInvalidSignature
 	}
+	t.sign(s)
+	return t, bc.addToPool(t)
+}
 
-	if bc.VerifyTransactionSignature(senderPublicKey, s, t) {
-		// if bc.CalculateTotalAmount(sender) < value {
-		// 	log.Println("ERROR: Not enough balance in a wallet")
-		// 	return false
-		// }
-		bc.transactionPool = append(bc.transactionPool, t)
-		return true
+// CreateBatchTransaction adds a transaction that pays every output at once.
+// It is accepted only if the sender can pay the total, so either all outputs are paid or none.
+func (bc *Blockchain) CreateBatchTransaction(sender string, outputs []*common.TransactionOutput, nonce uint64, s *TransactionSignature) (*Transaction, error) {
+	if err := common.ValidateOutputs(outputs); err != nil {
+		CountRejectedTransaction(REJECT_REASON_MALFORMED)
+		return nil, err
+	}
+	t := NewBatchTransaction(sender, outputs)
+	t.Nonce = nonce
+	if !bc.VerifyTransactionSignature(s, t) {
+		CountRejectedTransaction(REJECT_REASON_INVALID_SIGNATURE)
+		return nil, ErrInvalidSignature
 	}
-	log.Println("ERROR: Verify Transaction")
-	return false
+	t.sign(s)
+	return t, bc.addToPool(t)
 }
 
-func (bc *Blockchain) VerifyTransactionSignature(senderPublicKey *ecdsa.PublicKey, s *common.Signature, t *Transaction) bool {
-	m, _ := json.Marshal(t)
-	h := sha256.Sum256(m)
-	return ecdsa.Verify(senderPublicKey, h[:], s.R, s.S)
+// addToPool adds a signed transaction if the next block can include it after the transactions in the pool.
+// The rules are the same as the ones ValidChain applies to the blocks of other nodes.
+func (bc *Blockchain) addToPool(t *Transaction) error {
+	// 確認とpoolへの追加の間に、同じsenderの別のtransactionが入らないようにする
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	return bc.appendToPool(t)
 }
 
-func (bc *Blockchain) CopyTransactionFromPool() []*Transaction {
-	transaction := make([]*Transaction, 0, len(bc.transactionPool))
-	for _, t := range bc.transactionPool {
-		transaction = append(transaction, NewTransaction(
-			t.SenderBlockchainAddress,
-			t.RecipientBlockchainAddress,
-			t.Value,
-		))
+// appendToPool is addToPool for a caller that holds bc.mux.
+func (bc *Blockchain) appendToPool(t *Transaction) error {
+	l := bc.ledgerOf(bc.Chain)
+	p := l.next()
+	for _, pooled := range bc.transactionPool {
+		p.add(pooled)
+	}
+	if err := l.check(t, p); err != nil {
+		switch {
+		case errors.Is(err, ErrInsufficientBalance), errors.Is(err, ErrInsufficientStake):
+			CountRejectedTransaction(REJECT_REASON_INSUFFICIENT_BALANCE)
+		default:
+			CountRejectedTransaction(REJECT_REASON_MALFORMED)
+		}
+		return err
 	}
-	return transaction
+	bc.transactionPool = append(bc.transactionPool, t)
+	transactionsAccepted.Inc()
+	return nil
 }
 
-func (bc *Blockchain) LastBlock() *Block {
-	return bc.Chain[len(bc.Chain)-1]
+// NextNonce is the nonce of the next transaction of the sender, after its transactions in the pool.
+func (bc *Blockchain) NextNonce(sender string) uint64 {
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	l := bc.ledgerOf(bc.Chain)
+	p := l.next()
+	for _, t := range bc.transactionPool {
+		p.add(t)
+	}
+	return l.nonces[sender] + p.nonces[sender]
 }
 
-// マイニング競争に勝った者がブロックを生成するコンセンサスアルゴリズムの1種
-// TODO: 時間かかる
-func (bc *Blockchain) ValidProof(nonce int, previousHash string, transactions []*Transaction, difficulty int) bool {
-	zeros := strings.Repeat("0", difficulty)
-	guessBlock := Block{
-		Timestamp:    0,
-		Nonce:        nonce,
-		PreviousHash: previousHash,
-		Transactions: transactions,
+// pendingAmount is what the sender already spends in the transaction pool.
+func (bc *Blockchain) pendingAmount(sender string) float64 {
+	pending := 0.0
+	for _, t := range bc.transactionPool {
+		if t.SenderBlockchainAddress == sender && t.Type != common.TRANSACTION_TYPE_UNSTAKE {
+			pending += t.Value
+		}
 	}
-	guessHashStr := guessBlock.Hash()
-	return guessHashStr[:difficulty] == zeros
+	return pending
+}
+
+// TransactionSignature is the proof that the sender made a transaction.
+// The public key and the signature are in the encodings of the scheme.
+type TransactionSignature struct {
+	Scheme    common.SignatureScheme
+	PublicKey crypto.PublicKey
+	Signature []byte
 }
 
-// ProofOfWork finds nonce.
-func (bc *Blockchain) ProofOfWork() int {
-	// transactions := bc.CopyTransactionFromPool() // bc.transactionPoolじゃため？
-	transactions := bc.transactionPool
-	previousHash := bc.LastBlock().Hash()
-	nonce := 0
-	for !bc.ValidProof(nonce, previousHash, transactions, MINING_DIFFICULTY) {
-		nonce += 1
+func (bc *Blockchain) VerifyTransactionSignature(s *TransactionSignature, t *Transaction) bool {
+	if s == nil || s.Scheme == nil || s.PublicKey == nil {
+		return false
 	}
-	return nonce
+	start := time.Now()
+	ok := s.Scheme.Verify(s.PublicKey, t.Digest(), s.Signature)
+	signatureVerifySeconds.WithLabelValues(s.Scheme.ID()).Observe(time.Since(start).Seconds())
+	return ok
 }
 
-func (bc *Blockchain) Mining() bool {
+// FindTransaction looks for a transaction in the pool, then in the chain from the newest block.
+// The height is -1 while the transaction is in the pool.
+func (bc *Blockchain) FindTransaction(id string) (*Transaction, int, bool) {
 	bc.mux.Lock()
 	defer bc.mux.Unlock()
+	for _, t := range bc.transactionPool {
+		if t.ID() == id {
+			return t, -1, true
+		}
+	}
+	for height := len(bc.Chain) - 1; height >= 0; height-- {
+		for _, t := range bc.Chain[height].Transactions {
+			if t.ID() == id {
+				return t, height, true
+			}
+		}
+	}
+	return nil, 0, false
+}
 
+func (bc *Blockchain) LastBlock() *Block {
+	return bc.Chain[len(bc.Chain)-1]
+}
+
+// Mining seals a block of the transactions in the pool. The seal runs without the lock,
+// so the node keeps accepting transactions and chains meanwhile.
+func (bc *Blockchain) Mining() bool {
+	bc.mux.Lock()
 	// 空の場合はminingしない
 	if len(bc.transactionPool) == 0 {
+		bc.mux.Unlock()
+		miningAttempts.WithLabelValues(MINING_RESULT_EMPTY_POOL).Inc()
 		return false
 	}
+	chain := bc.Chain
+	if !bc.consensus.CanProduce(chain) {
+		bc.mux.Unlock()
+		miningAttempts.WithLabelValues(MINING_RESULT_NOT_PRODUCER).Inc()
+		return false
+	}
+	transactions := append([]*Transaction(nil), bc.transactionPool...)
+	// 送り手がBlockchainになる。max supplyに達した後はrewardなし
+	if reward := bc.nextReward(chain); reward > 0 {
+		transactions = append(transactions, NewTransaction(MINING_SENDER, bc.BlockchainAddress, reward))
+	}
+	b := NewBlock(0, bc.LastBlock().Hash(), transactions, bc.clock)
+	// 時計が遅れていても、直近のblockの中央値より後の時刻にする
+	if mtp := medianTimePast(chain); b.Timestamp <= mtp {
+		b.Timestamp = mtp + 1
+	}
+	bc.mux.Unlock()
 
-	// 送り手がBlockchainになる
-	bc.AddTransaction(MINING_SENDER, bc.BlockchainAddress, MINING_REWARD, nil, nil)
-	previousHash := bc.LastBlock().Hash()
-	nonce := bc.ProofOfWork()
-	bc.CreateBlock(nonce, previousHash)
+	if err := bc.consensus.Seal(chain, b); err != nil {
+		miningAttempts.WithLabelValues(MINING_RESULT_SEAL_FAILED).Inc()
+		log.Printf("action=mining, status=seal_failed, err=%v", err)
+		return false
+	}
+
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	// seal中に別のblockがつながった場合、このblockは捨てる。transactionはpoolに残っている
+	if bc.LastBlock().Hash() != b.PreviousHash {
+		miningAttempts.WithLabelValues(MINING_RESULT_STALE).Inc()
+		log.Println("action=mining, status=stale")
+		return false
+	}
+	bc.appendBlock(b)
+	miningAttempts.WithLabelValues(MINING_RESULT_SUCCESS).Inc()
 	log.Println("action=mining, status=success")
+
+	go bc.notifyNeighbors()
+	return true
+}
+
+// notifyNeighbors looks for the neighbors again and asks each of them to pull the new block.
+func (bc *Blockchain) notifyNeighbors() {
+	bc.SetNeighbors()
+	for _, n := range bc.Neighbors() {
+		go notifyConsensus(n)
+	}
+}
+
+// notifyConsensus asks a neighbor to pull the longest chain after a new block is mined.
+func notifyConsensus(neighbor string) {
+	endpoint := fmt.Sprintf("http://%s/v1/consensus", neighbor)
+	req, _ := http.NewRequest(http.MethodPut, endpoint, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// ValidChain checks that the chain starts from the genesis block of this node,
+// and that every block points to its parent, commits to its transactions,
+// has a valid timestamp, carries a valid seal and pays its producer.
+// It also replays the transactions: every one is signed by its sender, spends only what is spendable
+// at its block, and stakes or unstakes by the rules of the consensus.
+func (bc *Blockchain) ValidChain(chain []*Block) bool {
+	if len(chain) == 0 {
+		return false
+	}
+	if chain[0].Hash() != bc.genesisHash || chain[0].MerkleRoot != MerkleRoot(chain[0].Transactions) {
+		log.Printf("ERROR: %v", ErrGenesisMismatch)
+		return false
+	}
+	now := bc.clock.Now()
+	issued := issuedBy(chain[0])
+	l := newLedger(bc.params, bc.staking())
+	l.apply(chain[0])
+	for i := 1; i < len(chain); i++ {
+		b := chain[i]
+		if b.PreviousHash != chain[i-1].Hash() {
+			return false
+		}
+		if err := verifyTimestamp(chain[:i], b, now); err != nil {
+			log.Printf("ERROR: block %d: %v", i, err)
+			return false
+		}
+		if b.MerkleRoot != MerkleRoot(b.Transactions) {
+			log.Printf("ERROR: block %d: merkle root does not match the transactions", i)
+			return false
+		}
+		if err := bc.consensus.VerifySeal(chain[:i], b); err != nil {
+			log.Printf("ERROR: block %d: %v", i, err)
+			return false
+		}
+		if err := verifyReward(b, bc.params.Reward(i, issued)); err != nil {
+			log.Printf("ERROR: block %d: %v", i, err)
+			return false
+		}
+		if err := l.checkBlock(b); err != nil {
+			log.Printf("ERROR: block %d: %v", i, err)
+			return false
+		}
+		issued += issuedBy(b)
+	}
+	return true
+}
+
+// verifyTimestamp checks that b is later than the median of the last blocks of chain,
+// and not more than MAX_FUTURE_BLOCK_TIME_SEC ahead of now.
+// The median, not the parent, is the lower bound, so one block with a wrong clock doesn't hold the chain back.
+func verifyTimestamp(chain []*Block, b *Block, now time.Time) error {
+	if mtp := medianTimePast(chain); b.Timestamp <= mtp {
+		return fmt.Errorf("%w: %d is not after the median time %d of the last blocks", ErrInvalidTimestamp, b.Timestamp, mtp)
+	}
+	if limit := now.Add(time.Second * MAX_FUTURE_BLOCK_TIME_SEC); b.Timestamp > limit.UnixNano() {
+		return fmt.Errorf("%w: %d is too far in the future", ErrInvalidTimestamp, b.Timestamp)
+	}
+	return nil
+}
+
+// verifyReward checks that the block has at most one reward, that it is the reward of the schedule,
+// and that it goes to the producer. A block has no reward once the max supply is reached.
+// The seal covers the transactions, so nobody can take the reward of a block by signing it again.
+func verifyReward(b *Block, reward float64) error {
+	rewards := 0
+	for _, t := range b.Transactions {
+		if t.SenderBlockchainAddress != MINING_SENDER {
+			continue
+		}
+		rewards++
+		if rewards > 1 {
+			return fmt.Errorf("%w: more than one reward", ErrInvalidSeal)
+		}
+		if t.Value != reward || reward == 0 {
+			return fmt.Errorf("%w: reward %v, want %v", ErrInvalidSeal, t.Value, reward)
+		}
+		if t.RecipientBlockchainAddress != b.ProducerAddress() {
+			return fmt.Errorf("%w: reward to %s, not to the producer", ErrInvalidSeal, t.RecipientBlockchainAddress)
+		}
+	}
+	return nil
+}
+
+// ResolveConflicts replaces the chain with the valid chain among the neighbors that the fork choice prefers.
+func (bc *Blockchain) ResolveConflicts() bool {
+	bc.SetNeighbors()
+	var bestChain []*Block
+	bc.mux.Lock()
+	best := bc.Chain
+	bc.mux.Unlock()
+
+	for _, n := range bc.Neighbors() {
+		resp, err := http.Get(fmt.Sprintf("http://%s/v1/chain", n))
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			continue
+		}
+		if t, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+			bc.clock.AddSample(n, t)
+		}
+		var bcResp Blockchain
+		err = json.NewDecoder(resp.Body).Decode(&bcResp)
+		resp.Body.Close()
+		if err != nil {
+			log.Printf("ERROR: %v", err)
+			continue
+		}
+		chain := bcResp.Chain
+		if bc.consensus.ForkChoice(best, chain) && bc.ValidChain(chain) {
+			best = chain
+			bestChain = chain
+		}
+	}
+
+	if bestChain == nil {
+		log.Println("action=resolve_conflicts, status=not_replaced")
+		return false
+	}
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	// 取得中にblockを掘ったり別のchainに置き換えたりしているかもしれないので、今のchainと比べ直す
+	if !bc.consensus.ForkChoice(bc.Chain, bestChain) {
+		log.Println("action=resolve_conflicts, status=not_replaced")
+		return false
+	}
+	orphaned := bc.Chain[forkPoint(bc.Chain, bestChain):]
+	bc.Chain = bestChain
+	bc.reconcilePool(orphaned)
+	log.Println("action=resolve_conflicts, status=replaced")
 	return true
 }
 
+// forkPoint is the height of the first block where the chains differ.
+func forkPoint(a, b []*Block) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i].Hash() == b[i].Hash() {
+		i++
+	}
+	return i
+}
+
+// reconcilePool puts the transactions of the orphaned blocks back into the pool, and drops the transactions
+// that the new chain already has or can't include anymore. Rewards of the orphaned blocks are gone.
+func (bc *Blockchain) reconcilePool(orphaned []*Block) {
+	included := make(map[string]bool)
+	for _, b := range bc.Chain {
+		for _, t := range b.Transactions {
+			included[t.ID()] = true
+		}
+	}
+	var candidates []*Transaction
+	for _, b := range orphaned {
+		for _, t := range b.Transactions {
+			if t.SenderBlockchainAddress != MINING_SENDER {
+				candidates = append(candidates, t)
+			}
+		}
+	}
+	candidates = append(candidates, bc.transactionPool...)
+	l := bc.ledgerOf(bc.Chain)
+	p := l.next()
+	pool := make([]*Transaction, 0, len(candidates))
+	for _, t := range candidates {
+		if included[t.ID()] {
+			continue
+		}
+		included[t.ID()] = true
+		if err := l.check(t, p); err != nil {
+			log.Printf("action=reconcile_pool, transaction=%s, err=%v", t.ID(), err)
+			continue
+		}
+		pool = append(pool, t)
+	}
+	bc.transactionPool = pool
+}
+
 func (bc *Blockchain) StartMining() {
+	bc.mux.Lock()
+	bc.mining = true
+	bc.mux.Unlock()
 	bc.Mining()
 	// TODO: search wether available or not to use func which have returned value to time.AfterFunc argument.
-	_ = time.AfterFunc(time.Second*MINING_TIME_SEC, bc.StartMining)
+	_ = time.AfterFunc(bc.consensus.Interval(), bc.StartMining)
+}
+
+// Amount splits the balance of blockchainAddress into what it can spend, the rewards that are not mature yet,
+// and what its transactions in the pool spend.
+func (bc *Blockchain) Amount(blockchainAddress string) *common.AmountResponse {
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	return bc.amount(blockchainAddress)
+}
+
+func (bc *Blockchain) amount(blockchainAddress string) *common.AmountResponse {
+	total := bc.CalculateTotalAmount(blockchainAddress)
+	immature := bc.immatureAmount(blockchainAddress)
+	pending := bc.pendingAmount(blockchainAddress)
+	return &common.AmountResponse{
+		Amount:    total,
+		Spendable: total - immature - pending,
+		Immature:  immature,
+		Pending:   pending,
+	}
 }
 
+// immatureAmount is the rewards of blockchainAddress that can't be spent yet,
+// because their blocks may still be replaced by another chain. The premine of the genesis block is always mature.
+func (bc *Blockchain) immatureAmount(blockchainAddress string) float64 {
+	immature := 0.0
+	start := len(bc.Chain) - bc.params.CoinbaseMaturity + 1
+	if start < 1 {
+		start = 1
+	}
+	if start >= len(bc.Chain) {
+		return 0
+	}
+	for _, b := range bc.Chain[start:] {
+		for _, t := range b.Transactions {
+			if t.SenderBlockchainAddress == MINING_SENDER {
+				immature += t.ReceivedBy(blockchainAddress)
+			}
+		}
+	}
+	return immature
+}
+
+// CalculateTotalAmount is the balance that is not staked. It includes immature rewards.
+// An unstake transaction pays the stake back only when its lock-up period is over.
 func (bc *Blockchain) CalculateTotalAmount(blockchainAddress string) float64 {
 	totalAmount := 0.0
 	for _, block := range bc.Chain {
 		for _, t := range block.Transactions {
-			value := t.Value
-			if blockchainAddress == t.RecipientBlockchainAddress {
-				totalAmount += value
-			}
-			if blockchainAddress == t.SenderBlockchainAddress {
-				totalAmount -= value
+			totalAmount += t.ReceivedBy(blockchainAddress)
+			if blockchainAddress == t.SenderBlockchainAddress && t.Type != common.TRANSACTION_TYPE_UNSTAKE {
+				totalAmount -= t.Value
 			}
 		}
 	}
-	return totalAmount
+	return totalAmount + NewStakeLedger(bc.Chain).Released[blockchainAddress]
 }
 
 // block内のtransaction
+// batch transactionはOutputsの全員に送る。RecipientBlockchainAddressは空で、ValueはOutputsの合計。
+// stake, unstake, slashはTypeを持ち、RecipientBlockchainAddressは空。slashは署名の代わりにEvidenceを持つ。
+// 署名を除いたJSONは署名対象のcommon.TransactionMessageと同じ形にする。
+// 署名と公開鍵もblockに入れて、他のnodeがchainを検証できるようにする。
 type Transaction struct {
-	SenderBlockchainAddress    string  `json:"sender_blockchain_address"`
-	RecipientBlockchainAddress string  `json:"recipient_blockchain_address"`
-	Value                      float64 `json:"value"`
+	SenderBlockchainAddress    string                      `json:"sender_blockchain_address"`
+	RecipientBlockchainAddress string                      `json:"recipient_blockchain_address"`
+	Value                      float64                     `json:"value"`
+	Outputs                    []*common.TransactionOutput `json:"outputs,omitempty"`
+	Type                       string                      `json:"type,omitempty"`
+	Nonce                      uint64                      `json:"nonce"`
+	Evidence                   *SlashEvidence              `json:"evidence,omitempty"`
+	SenderPublicKey            string                      `json:"sender_public_key,omitempty"`
+	Signature                  string                      `json:"signature,omitempty"`
+	SignatureScheme            string                      `json:"signature_scheme,omitempty"`
 }
 
 func NewTransaction(sender, recipient string, value float64) *Transaction {
@@ -219,35 +758,175 @@ func NewTransaction(sender, recipient string, value float64) *Transaction {
 	}
 }
 
+func NewBatchTransaction(sender string, outputs []*common.TransactionOutput) *Transaction {
+	return &Transaction{
+		SenderBlockchainAddress: sender,
+		Value:                   common.OutputsTotal(outputs),
+		Outputs:                 outputs,
+	}
+}
+
+// NewStakeTransaction stakes or unstakes value of the sender.
+func NewStakeTransaction(sender, typ string, value float64) *Transaction {
+	return &Transaction{
+		SenderBlockchainAddress: sender,
+		Value:                   value,
+		Type:                    typ,
+	}
+}
+
+// NewSlashTransaction burns the stake of the producer who signed both blocks of the evidence.
+func NewSlashTransaction(offender string, e *SlashEvidence) *Transaction {
+	return &Transaction{
+		SenderBlockchainAddress: offender,
+		Type:                    common.TRANSACTION_TYPE_SLASH,
+		Evidence:                e,
+	}
+}
+
+// Digest is what the sender signs. The JSON of Transaction without the signature is the same as common.TransactionMessage.
+func (t *Transaction) Digest() []byte {
+	unsigned := *t
+	unsigned.SenderPublicKey, unsigned.Signature, unsigned.SignatureScheme = "", "", ""
+	m, _ := json.Marshal(&unsigned)
+	h := sha256.Sum256(m)
+	return h[:]
+}
+
+// ID is the same as common.TransactionMessage.ID, so it doesn't depend on the signature.
+func (t *Transaction) ID() string {
+	return hex.EncodeToString(t.Digest())
+}
+
+// ReceivedBy returns how much the transaction pays to blockchainAddress.
+func (t *Transaction) ReceivedBy(blockchainAddress string) float64 {
+	if len(t.Outputs) == 0 {
+		if t.RecipientBlockchainAddress == blockchainAddress {
+			return t.Value
+		}
+		return 0
+	}
+	received := 0.0
+	for _, o := range t.Outputs {
+		if o.RecipientBlockchainAddress == blockchainAddress {
+			received += o.Value
+		}
+	}
+	return received
+}
+
 func (t *Transaction) Print() {
 	fmt.Printf("senderBlockchainAddress    %s\n", t.SenderBlockchainAddress)
 	fmt.Printf("recipientBlockchainAddress %s\n", t.RecipientBlockchainAddress)
 	fmt.Printf("value                      %v\n", t.Value)
+	for _, o := range t.Outputs {
+		fmt.Printf("  output %s %v\n", o.RecipientBlockchainAddress, o.Value)
+	}
 }
 
+// Outputsを指定した場合はbatch transactionになる。
+// Typeを指定した場合はstakeまたはunstakeになる。RecipientBlockchainAddressは空にする。
 type BlockchainTransactionRequest struct {
-	SenderBlockchainAddress    string  `json:"sender_blockchain_address"`
-	RecipientBlockchainAddress string  `json:"recipient_blockchain_address"`
-	SenderPublicKey            string  `json:"sender_public_key"`
-	Value                      float64 `json:"value"`
-	Signature                  string  `json:"signature"`
+	SenderBlockchainAddress    string                      `json:"sender_blockchain_address"`
+	RecipientBlockchainAddress string                      `json:"recipient_blockchain_address"`
+	SenderPublicKey            string                      `json:"sender_public_key"`
+	Value                      float64                     `json:"value"`
+	Outputs                    []*common.TransactionOutput `json:"outputs"`
+	Signature                  string                      `json:"signature"`
+	// SignatureScheme is ecdsa-p256, ecdsa-secp256k1 or ed25519. Empty means the scheme of the sender address.
+	SignatureScheme string `json:"signature_scheme"`
+	Type            string `json:"type"`
+	Nonce           uint64 `json:"nonce"` // see GET /nonce
 }
 
 func (t BlockchainTransactionRequest) Validate() error {
 	return validation.ValidateStruct(&t,
 		validation.Field(&t.SenderBlockchainAddress, validation.Required, validation.Length(26, 35)),
-		validation.Field(&t.RecipientBlockchainAddress, validation.Required, validation.Length(26, 35)),
-		validation.Field(&t.SenderPublicKey, validation.Required, validation.Length(128, 128)),
+		validation.Field(&t.RecipientBlockchainAddress, validation.Required.When(t.Type == "" && len(t.Outputs) == 0), validation.Length(26, 35), validation.By(validateAddress)),
+		validation.Field(&t.SenderPublicKey, validation.Required), // see ParsePublicKey of the scheme
 		validation.Field(&t.Value, validation.Required),
-		validation.Field(&t.Signature, validation.Required, validation.Length(128, 128)),
+		validation.Field(&t.Outputs, validation.When(len(t.Outputs) > 0, validation.By(validateOutputs))),
+		validation.Field(&t.Signature, validation.Required), // see ParseSignature of the scheme
+		validation.Field(&t.SignatureScheme, validation.In(common.SignatureSchemes...)),
+		validation.Field(&t.Type, validation.In(common.StakeTransactionTypes...)),
 	)
 }
 
+// validateAddress rejects the addresses of the other networks, see common.SetAddressVersion.
+func validateAddress(v interface{}) error {
+	s, _ := v.(string)
+	if s == "" {
+		return nil
+	}
+	return common.ValidateAddress(s)
+}
+
+func validateOutputs(v interface{}) error {
+	outputs, _ := v.([]*common.TransactionOutput)
+	return common.ValidateOutputs(outputs)
+}
+
+type TransactionCreatedResponse struct {
+	ID string `json:"id"`
+}
+
+// TransactionDetailResponse is a transaction found by its ID.
+// BlockHeight is nil while the transaction is in the pool.
+type TransactionDetailResponse struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"` // pending or confirmed
+	BlockHeight *int   `json:"block_height"`
+	*Transaction
+}
+
+const (
+	TRANSACTION_STATUS_PENDING   = "pending"
+	TRANSACTION_STATUS_CONFIRMED = "confirmed"
+)
+
 type BlockchainTransactionResponse struct {
 	Transactions []*Transaction `json:"transactions"`
 	Length       int            `json:"length"`
 }
 
-type AmountResponse struct {
-	Amount float64 `json:"amount"`
+// ChainResponse is GET /chain. It is read under the lock, so it does not change while it is serialized.
+type ChainResponse struct {
+	Chain             []*Block `json:"chains"`
+	BlockchainAddress string
+}
+
+// BlockResponse is a block with its position in the chain. Height of the genesis block is 0.
+// ProducerAddress is empty for the genesis block.
+type BlockResponse struct {
+	Height          int    `json:"height"`
+	Hash            string `json:"hash"`
+	ProducerAddress string `json:"producer_address,omitempty"`
+	*Block
+}
+
+type NeighborsResponse struct {
+	Neighbors []string `json:"neighbors"`
+	Length    int      `json:"length"`
+}
+
+type HealthResponse struct {
+	Status            string `json:"status"`
+	NodeAddress       string `json:"node_address"`
+	BlockchainAddress string `json:"blockchain_address"`
+	ChainHeight       int    `json:"chain_height"`
+	TipHash           string `json:"tip_hash"`
+	PoolSize          int    `json:"pool_size"`
+	Mining            bool   `json:"mining"`
+	Peers             int    `json:"peers"`
+	Storage           string `json:"storage"`
+	Consensus         string `json:"consensus"`
+	TimeOffsetMs      int64  `json:"time_offset_ms"` // network-adjusted time minus the local time
+	Network           string `json:"network"`
+	ChainID           string `json:"chain_id"`
+	GenesisHash       string `json:"genesis_hash"`
+}
+
+type NonceResponse struct {
+	BlockchainAddress string `json:"blockchain_address"`
+	Nonce             uint64 `json:"nonce"` // nonce of the next transaction of the address
 }
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	MEDIAN_TIME_BLOCKS        = 11          // number of blocks whose median timestamp a new block must be later than.
+	MAX_FUTURE_BLOCK_TIME_SEC = 2 * 60 * 60 // how far a block may be ahead of the network-adjusted time.
+	MAX_TIME_OFFSET_SEC       = 70 * 60     // largest adjustment of the local clock by the neighbors.
+)
+
+// Clock is the source of the current time of a node.
+// Tests can drive a simulated clock instead of the system clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the clock of the machine.
+var SystemClock Clock = systemClock{}
+
+// NetworkClock is the local clock adjusted by the median of the offsets of the neighbors.
+// The local clock counts as a neighbor with no offset, so a single neighbor can't move the time alone.
+type NetworkClock struct {
+	clock   Clock
+	mux     sync.Mutex
+	offsets map[string]time.Duration // neighbor -> its time minus the local time
+}
+
+func NewNetworkClock(clock Clock) *NetworkClock {
+	return &NetworkClock{
+		clock:   clock,
+		offsets: make(map[string]time.Duration),
+	}
+}
+
+// AddSample records the time a neighbor reported. The latest sample of each neighbor is kept.
+func (n *NetworkClock) AddSample(neighbor string, t time.Time) {
+	offset := t.Sub(n.clock.Now())
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	n.offsets[neighbor] = offset
+}
+
+// Offset is the median offset, or 0 if it is larger than MAX_TIME_OFFSET_SEC.
+// Such a difference means the local clock is wrong, and following the neighbors would hide it.
+func (n *NetworkClock) Offset() time.Duration {
+	n.mux.Lock()
+	offsets := []time.Duration{0}
+	for _, o := range n.offsets {
+		offsets = append(offsets, o)
+	}
+	n.mux.Unlock()
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	median := offsets[len(offsets)/2]
+	// of the two middle offsets of an even count, the one closer to the local clock
+	if len(offsets)%2 == 0 {
+		if lower := offsets[len(offsets)/2-1]; abs(lower) < abs(median) {
+			median = lower
+		}
+	}
+	if median > time.Second*MAX_TIME_OFFSET_SEC || median < -time.Second*MAX_TIME_OFFSET_SEC {
+		return 0
+	}
+	return median
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func (n *NetworkClock) Now() time.Time {
+	return n.clock.Now().Add(n.Offset())
+}
+
+// medianTimePast is the median timestamp of the last MEDIAN_TIME_BLOCKS blocks of chain.
+// The genesis block is not produced by anyone, so it doesn't count, except that the first block
+// must still be after it: with no other block, it is the timestamp of the genesis block.
+func medianTimePast(chain []*Block) int64 {
+	start := len(chain) - MEDIAN_TIME_BLOCKS
+	if start < 1 {
+		start = 1
+	}
+	if start >= len(chain) {
+		return chain[0].Timestamp
+	}
+	timestamps := make([]int64, 0, len(chain)-start)
+	for _, b := range chain[start:] {
+		timestamps = append(timestamps, b.Timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2]
+}
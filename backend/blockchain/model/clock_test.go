@@ -0,0 +1,138 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that stands still until the test moves it.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// chainAt is a chain of a genesis block and one block at each of the timestamps.
+func chainAt(timestamps ...int64) []*Block {
+	chain := []*Block{{Timestamp: DEFAULT_GENESIS_TIMESTAMP}}
+	for _, ts := range timestamps {
+		chain = append(chain, &Block{Timestamp: ts})
+	}
+	return chain
+}
+
+func TestMedianTimePast(t *testing.T) {
+	tests := []struct {
+		name  string
+		chain []*Block
+		want  int64
+	}{
+		// the first block must be after the genesis block
+		{"genesis only", chainAt(), DEFAULT_GENESIS_TIMESTAMP},
+		{"one block", chainAt(100), 100},
+		{"unordered", chainAt(300, 100, 200), 200},
+		// the genesis block is not produced by anyone, so its timestamp doesn't count
+		{"genesis excluded", chainAt(5, 6), 6},
+		// only the last MEDIAN_TIME_BLOCKS count: 1..3 are older than the window
+		{"window", chainAt(1, 2, 3, 110, 120, 130, 140, 150, 160, 170, 180, 190, 200, 210), 160},
+	}
+	for _, tt := range tests {
+		if got := medianTimePast(tt.chain); got != tt.want {
+			t.Errorf("%s: medianTimePast = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTimestamp(t *testing.T) {
+	now := time.Unix(0, DEFAULT_GENESIS_TIMESTAMP).Add(24 * time.Hour)
+	limit := now.Add(time.Second * MAX_FUTURE_BLOCK_TIME_SEC).UnixNano()
+	chain := chainAt(now.UnixNano()-3000, now.UnixNano()-1000, now.UnixNano()-2000)
+	mtp := medianTimePast(chain)
+
+	tests := []struct {
+		name      string
+		timestamp int64
+		valid     bool
+	}{
+		{"at the median", mtp, false},
+		{"before the median", mtp - 1, false},
+		// earlier than the parent is fine as long as it is after the median
+		{"after the median", mtp + 1, true},
+		{"at the future limit", limit, true},
+		{"beyond the future limit", limit + 1, false},
+	}
+	for _, tt := range tests {
+		err := verifyTimestamp(chain, &Block{Timestamp: tt.timestamp}, now)
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidTimestamp) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, ErrInvalidTimestamp)
+		}
+	}
+
+	// the first block is checked against the genesis block
+	genesis := chainAt()
+	if err := verifyTimestamp(genesis, &Block{Timestamp: DEFAULT_GENESIS_TIMESTAMP - 1}, now); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Errorf("first block before the genesis block: err = %v, want %v", err, ErrInvalidTimestamp)
+	}
+}
+
+func TestNetworkClock(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, DEFAULT_GENESIS_TIMESTAMP)}
+	nc := NewNetworkClock(clock)
+	if got := nc.Offset(); got != 0 {
+		t.Fatalf("Offset without neighbors = %v, want 0", got)
+	}
+
+	// the local clock counts as a sample with no offset, so one neighbor alone doesn't move the time
+	nc.AddSample("a", clock.now.Add(10*time.Minute))
+	if got := nc.Offset(); got != 0 {
+		t.Errorf("Offset of {0, 10m} = %v, want 0", got)
+	}
+	nc.AddSample("b", clock.now.Add(-5*time.Minute))
+	if got := nc.Offset(); got != 0 {
+		t.Errorf("Offset of {-5m, 0, 10m} = %v, want 0", got)
+	}
+	// a new sample replaces the previous one of the neighbor
+	nc.AddSample("b", clock.now.Add(20*time.Minute))
+	if got := nc.Offset(); got != 10*time.Minute {
+		t.Errorf("Offset of {0, 10m, 20m} = %v, want %v", got, 10*time.Minute)
+	}
+	if got, want := nc.Now(), clock.now.Add(10*time.Minute); !got.Equal(want) {
+		t.Errorf("Now = %v, want %v", got, want)
+	}
+
+	// an offset beyond MAX_TIME_OFFSET_SEC means the local clock is wrong, so it is not followed
+	far := time.Second*MAX_TIME_OFFSET_SEC + time.Minute
+	nc.AddSample("a", clock.now.Add(far))
+	nc.AddSample("b", clock.now.Add(far))
+	if got := nc.Offset(); got != 0 {
+		t.Errorf("Offset beyond the max = %v, want 0", got)
+	}
+}
+
+// The future limit follows the network-adjusted time, so a node whose clock is behind
+// still accepts the blocks of its neighbors.
+func TestVerifyTimestampNetworkTime(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, DEFAULT_GENESIS_TIMESTAMP).Add(24 * time.Hour)}
+	nc := NewNetworkClock(clock)
+	chain := chainAt(clock.now.UnixNano() - 1000)
+	b := &Block{Timestamp: clock.now.Add(time.Second*MAX_FUTURE_BLOCK_TIME_SEC + 30*time.Minute).UnixNano()}
+
+	if err := verifyTimestamp(chain, b, nc.Now()); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Fatalf("local time: err = %v, want %v", err, ErrInvalidTimestamp)
+	}
+	nc.AddSample("a", clock.now.Add(time.Hour))
+	nc.AddSample("b", clock.now.Add(time.Hour))
+	if err := verifyTimestamp(chain, b, nc.Now()); err != nil {
+		t.Errorf("network time: %v", err)
+	}
+	// NewBlock takes its timestamp from the clock it is given
+	if got := NewBlock(0, "", nil, nc).Timestamp; got != clock.now.Add(time.Hour).UnixNano() {
+		t.Errorf("NewBlock timestamp = %d, want %d", got, clock.now.Add(time.Hour).UnixNano())
+	}
+}
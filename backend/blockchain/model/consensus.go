@@ -0,0 +1,101 @@
+package model
+
+import (
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+const (
+	CONSENSUS_POW = "pow"
+	CONSENSUS_POA = "poa"
+	CONSENSUS_POS = "pos"
+)
+
+var (
+	ErrUnknownConsensus = errors.New("unknown consensus")
+	ErrInvalidSeal      = errors.New("invalid seal")
+)
+
+// Consensus is a consensus algorithm that Blockchain delegates to.
+// chain is always the blocks before b, so the parent of b is the last one.
+type Consensus interface {
+	Name() string
+	// Interval is how often the node tries to produce a block.
+	Interval() time.Duration
+	// CanProduce reports whether this node may produce the block on top of chain now.
+	CanProduce(chain []*Block) bool
+	// Seal fills the fields of b that prove it was produced by the rules, e.g. the nonce of PoW.
+	Seal(chain []*Block, b *Block) error
+	// VerifySeal checks the seal of a block received from a neighbor.
+	VerifySeal(chain []*Block, b *Block) error
+	// ForkChoice reports whether candidate should replace current.
+	// Both chains are already verified.
+	ForkChoice(current, candidate []*Block) bool
+}
+
+// ConsensusConfig is what the consensus engines need from the flags of the node.
+type ConsensusConfig struct {
+	Validators    []string      // public keys of the genesis validators of poa
+	PrivateKey    crypto.Signer // key of this node of any signature scheme, which signs the blocks it produces
+	Clock         Clock         // time of the slots of poa and pos, the same clock as the blockchain's
+	Difficulty    int           // difficulty of pow, from the chain params
+	GenesisStakes []*Allocation // stakes of the genesis stakers of pos, from the chain params
+}
+
+// setProducer sets the public key of privateKey as the producer. The header covers it,
+// so pow sets it before the proof of work.
+func setProducer(b *Block, privateKey crypto.Signer) (common.SignatureScheme, error) {
+	scheme, err := common.SchemeOfPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	b.Producer = scheme.EncodePublicKey(privateKey.Public())
+	return scheme, nil
+}
+
+// signBlock sets the public key of privateKey as the producer and signs SealHash
+// with the scheme of the key. Every field of the header must be set before.
+func signBlock(b *Block, privateKey crypto.Signer) error {
+	scheme, err := setProducer(b, privateKey)
+	if err != nil {
+		return err
+	}
+	b.Signature = hex.EncodeToString(scheme.Sign(privateKey, b.SealHash()))
+	return nil
+}
+
+// NewConsensus returns the consensus chosen by the -consensus flag of the node.
+func NewConsensus(name string, cfg *ConsensusConfig) (Consensus, error) {
+	switch name {
+	case CONSENSUS_POW:
+		return NewProofOfWork(cfg.Difficulty, cfg.PrivateKey), nil
+	case CONSENSUS_POA:
+		return NewProofOfAuthority(cfg.Validators, cfg.PrivateKey, time.Second*POA_PERIOD_SEC, cfg.Clock)
+	case CONSENSUS_POS:
+		return NewProofOfStake(cfg.GenesisStakes, cfg.PrivateKey, time.Second*POS_PERIOD_SEC, cfg.Clock)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownConsensus, name)
+	}
+}
+
+// verifyProducerSignature checks that Signature is the signature of Producer over SealHash.
+func verifyProducerSignature(b *Block) error {
+	_, pub, err := common.ParseAnyPublicKey(b.Producer)
+	if err != nil {
+		return fmt.Errorf("%w: producer is not a public key in the encoding of its scheme", ErrInvalidSeal)
+	}
+	scheme, err := common.SchemeOfPublicKey(pub)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSeal, err)
+	}
+	sig, err := scheme.ParseSignature(b.Signature)
+	if err != nil || !scheme.Verify(pub, b.SealHash(), sig) {
+		return fmt.Errorf("%w: signature of the producer", ErrInvalidSeal)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package model
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+var (
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	ErrInvalidNonce       = errors.New("invalid nonce")
+	ErrAlreadySlashed     = errors.New("already slashed")
+)
+
+// ledger is the state of the accounts replayed from the transactions of a chain.
+// ValidChain checks every block against the ledger of the blocks before it,
+// and the pool is checked against the ledger of the chain in the same way, as if it were the next block.
+type ledger struct {
+	balances map[string]float64 // received minus spent, without the released stake
+	stakes   *StakeLedger
+	rewards  []map[string]float64 // rewards of each block by recipient, to tell the immature ones
+	nonces   map[string]uint64    // nonce of the next transaction of each sender
+	maturity int
+	staking  bool // stake transactions are only valid with the pos consensus
+}
+
+// pending is what the senders already spend in the block being checked.
+type pending struct {
+	spent    map[string]float64
+	unstaked map[string]float64
+	nonces   map[string]uint64 // transactions of each sender
+	slashed  map[string]bool
+}
+
+func newLedger(params *ChainParams, staking bool) *ledger {
+	return &ledger{
+		balances: make(map[string]float64),
+		stakes:   newStakeLedger(),
+		nonces:   make(map[string]uint64),
+		maturity: params.CoinbaseMaturity,
+		staking:  staking,
+	}
+}
+
+// ledgerOf replays a chain that this node already validated, so the transactions are not checked again.
+func (bc *Blockchain) ledgerOf(chain []*Block) *ledger {
+	l := newLedger(bc.params, bc.staking())
+	for _, b := range chain {
+		l.apply(b)
+	}
+	return l
+}
+
+func (bc *Blockchain) staking() bool {
+	_, ok := bc.consensus.(*ProofOfStake)
+	return ok
+}
+
+// height is the height of the next block.
+func (l *ledger) height() int {
+	return len(l.rewards)
+}
+
+// spendable is the same as common.AmountResponse.Spendable without the pool: the balance minus the immature rewards.
+func (l *ledger) spendable(address string) float64 {
+	return l.balances[address] + l.stakes.Released[address] - l.immature(address)
+}
+
+// immature is the rewards of the last CoinbaseMaturity-1 blocks, see Blockchain.immatureAmount.
+func (l *ledger) immature(address string) float64 {
+	start := l.height() - l.maturity + 1
+	if start < 1 {
+		start = 1
+	}
+	immature := 0.0
+	for h := start; h < l.height(); h++ {
+		immature += l.rewards[h][address]
+	}
+	return immature
+}
+
+// next starts the next block: the stakes whose lock-up period is over at its height can be spent in it.
+func (l *ledger) next() *pending {
+	l.stakes.release(l.height())
+	return &pending{
+		spent:    make(map[string]float64),
+		unstaked: make(map[string]float64),
+		nonces:   make(map[string]uint64),
+		slashed:  make(map[string]bool),
+	}
+}
+
+// check tells whether the next block can include t after the transactions already in p, and adds t to p.
+// The signature is checked by the caller. Rewards are not checked here, see verifyReward.
+func (l *ledger) check(t *Transaction, p *pending) error {
+	sender := t.SenderBlockchainAddress
+	if sender == MINING_SENDER {
+		return fmt.Errorf("%w: a reward outside of the block reward", ErrInvalidTransaction)
+	}
+	switch t.Type {
+	case "":
+		if err := checkPayment(t); err != nil {
+			return err
+		}
+	case common.TRANSACTION_TYPE_STAKE, common.TRANSACTION_TYPE_UNSTAKE:
+		if !l.staking {
+			return ErrStakingDisabled
+		}
+		if !(t.Value > 0) || t.RecipientBlockchainAddress != "" || len(t.Outputs) > 0 {
+			return fmt.Errorf("%w: %s needs a positive value and no recipient", ErrInvalidTransaction, t.Type)
+		}
+		// a slashed address can't stake again, otherwise it could double sign once more without losing anything
+		if t.Type == common.TRANSACTION_TYPE_STAKE && l.stakes.Slashed[sender] {
+			return fmt.Errorf("%w: %s can't stake", ErrAlreadySlashed, sender)
+		}
+	case common.TRANSACTION_TYPE_SLASH:
+		// the evidence is signed by the offender, and VerifySeal checks it. A slash only burns the stake.
+		if !l.staking {
+			return ErrStakingDisabled
+		}
+		if t.Evidence == nil {
+			return fmt.Errorf("%w: slash without evidence", ErrInvalidTransaction)
+		}
+		if t.Value != 0 || t.RecipientBlockchainAddress != "" || len(t.Outputs) > 0 {
+			return fmt.Errorf("%w: a slash moves no coins", ErrInvalidTransaction)
+		}
+		// the evidence names the offender, so this also keeps the same evidence from being used twice
+		if l.stakes.Slashed[sender] || p.slashed[sender] {
+			return fmt.Errorf("%w: %s", ErrAlreadySlashed, sender)
+		}
+		p.add(t)
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidTransaction, t.Type)
+	}
+	// 同じnonceのtransactionは一度しか入らないので、署名済みのtransactionを再送できない
+	if next := l.nonces[sender] + p.nonces[sender]; t.Nonce != next {
+		return fmt.Errorf("%w: %d, the next nonce of %s is %d", ErrInvalidNonce, t.Nonce, sender, next)
+	}
+
+	if t.Type == common.TRANSACTION_TYPE_UNSTAKE {
+		if staked := l.stakes.Staked[sender] - p.unstaked[sender]; staked < t.Value {
+			return fmt.Errorf("%w: %v staked, %v required", ErrInsufficientStake, staked, t.Value)
+		}
+	} else if available := l.spendable(sender) - p.spent[sender]; available < t.Value {
+		return fmt.Errorf("%w: %v available, %v required", ErrInsufficientBalance, available, t.Value)
+	}
+	p.add(t)
+	return nil
+}
+
+func (p *pending) add(t *Transaction) {
+	if t.Type == common.TRANSACTION_TYPE_SLASH {
+		p.slashed[t.SenderBlockchainAddress] = true
+		return
+	}
+	p.nonces[t.SenderBlockchainAddress]++
+	if t.Type == common.TRANSACTION_TYPE_UNSTAKE {
+		p.unstaked[t.SenderBlockchainAddress] += t.Value
+		return
+	}
+	p.spent[t.SenderBlockchainAddress] += t.Value
+}
+
+func checkPayment(t *Transaction) error {
+	if len(t.Outputs) > 0 {
+		if err := common.ValidateOutputs(t.Outputs); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+		}
+		if t.RecipientBlockchainAddress != "" || t.Value != common.OutputsTotal(t.Outputs) {
+			return fmt.Errorf("%w: the value of a batch is the total of its outputs", ErrInvalidTransaction)
+		}
+		return nil
+	}
+	if err := common.ValidateAddress(t.RecipientBlockchainAddress); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+	if !(t.Value > 0) {
+		return fmt.Errorf("%w: a payment needs a positive value", ErrInvalidTransaction)
+	}
+	return nil
+}
+
+// checkBlock checks the signature of every transaction of b and checks it against the ledger,
+// then applies the block.
+func (l *ledger) checkBlock(b *Block) error {
+	p := l.next()
+	for _, t := range b.Transactions {
+		if t.SenderBlockchainAddress == MINING_SENDER {
+			continue
+		}
+		if t.Type != common.TRANSACTION_TYPE_SLASH {
+			if err := t.VerifySignature(); err != nil {
+				return fmt.Errorf("transaction %s: %w", t.ID(), err)
+			}
+		}
+		if err := l.check(t, p); err != nil {
+			return fmt.Errorf("transaction %s: %w", t.ID(), err)
+		}
+	}
+	l.applyTransactions(b)
+	return nil
+}
+
+// apply adds b to the ledger without checking it.
+func (l *ledger) apply(b *Block) {
+	l.next()
+	l.applyTransactions(b)
+}
+
+func (l *ledger) applyTransactions(b *Block) {
+	height := l.height()
+	rewards := make(map[string]float64)
+	for _, t := range b.Transactions {
+		if t.SenderBlockchainAddress == MINING_SENDER && height > 0 {
+			rewards[t.RecipientBlockchainAddress] += t.Value
+		}
+		if t.Type == common.TRANSACTION_TYPE_SLASH {
+			l.stakes.apply(height, t)
+			continue
+		}
+		if len(t.Outputs) > 0 {
+			for _, o := range t.Outputs {
+				l.balances[o.RecipientBlockchainAddress] += o.Value
+			}
+		} else if t.RecipientBlockchainAddress != "" {
+			l.balances[t.RecipientBlockchainAddress] += t.Value
+		}
+		if t.SenderBlockchainAddress != MINING_SENDER && t.Type != common.TRANSACTION_TYPE_UNSTAKE {
+			l.balances[t.SenderBlockchainAddress] -= t.Value
+		}
+		if t.SenderBlockchainAddress != MINING_SENDER {
+			l.nonces[t.SenderBlockchainAddress]++
+		}
+		l.stakes.apply(height, t)
+	}
+	l.rewards = append(l.rewards, rewards)
+}
+
+// VerifySignature checks that the public key of t is the sender's and that it signed t.
+func (t *Transaction) VerifySignature() error {
+	scheme, err := common.SchemeOfTransaction(t.SignatureScheme, t.SenderBlockchainAddress)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	publicKey, err := scheme.ParsePublicKey(t.SenderPublicKey)
+	if err != nil || scheme.Address(publicKey) != t.SenderBlockchainAddress {
+		return ErrInvalidSignature
+	}
+	signature, err := scheme.ParseSignature(t.Signature)
+	if err != nil || !scheme.Verify(publicKey, t.Digest(), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// sign attaches a signature that VerifyTransactionSignature accepted, so that the block carries it.
+func (t *Transaction) sign(s *TransactionSignature) {
+	t.SenderPublicKey = s.Scheme.EncodePublicKey(s.PublicKey)
+	t.Signature = hex.EncodeToString(s.Signature)
+	t.SignatureScheme = s.Scheme.ID()
+}
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+// A reward of the block at height h can be spent from height h + CoinbaseMaturity.
+// The premine of the genesis block is mature from the start.
+func TestCoinbaseMaturity(t *testing.T) {
+	miner, err := NewWallet(schemeOf(t, common.SCHEME_ECDSA_P256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := NewWallet(schemeOf(t, common.SCHEME_ECDSA_P256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := &ChainParams{
+		Premine:          []*Allocation{{BlockchainAddress: miner.BlockchainAddress(), Value: 5}},
+		BlockReward:      1,
+		CoinbaseMaturity: 3,
+	}
+	chain := []*Block{params.GenesisBlock()}
+	l := newLedger(params, false)
+	l.apply(chain[0])
+	bc := &Blockchain{Chain: chain, params: params}
+
+	// spendable and immature before the block at each height, after the rewards of the blocks below it
+	tests := []struct {
+		height    int
+		spendable float64
+		immature  float64
+	}{
+		{1, 5, 0},
+		{2, 5, 1}, // the reward of 1
+		{3, 5, 2}, // 1 and 2
+		{4, 6, 2}, // 1 is mature at 1+3
+		{5, 7, 2},
+	}
+	for _, tt := range tests {
+		if h := l.height(); h != tt.height {
+			t.Fatalf("height %d, want %d", h, tt.height)
+		}
+		if got := l.immature(miner.BlockchainAddress()); got != tt.immature {
+			t.Errorf("height %d: ledger immature = %v, want %v", tt.height, got, tt.immature)
+		}
+		if got := l.spendable(miner.BlockchainAddress()); got != tt.spendable {
+			t.Errorf("height %d: ledger spendable = %v, want %v", tt.height, got, tt.spendable)
+		}
+		// the amount API counts the same rewards as the ledger that validates the blocks
+		bc.Chain = chain
+		if got := bc.immatureAmount(miner.BlockchainAddress()); got != tt.immature {
+			t.Errorf("height %d: immatureAmount = %v, want %v", tt.height, got, tt.immature)
+		}
+
+		// spending one more than the spendable balance fails while the rest is immature
+		pay := NewTransaction(miner.BlockchainAddress(), recipient.BlockchainAddress(), tt.spendable+1)
+		pay.Nonce = l.nonces[miner.BlockchainAddress()]
+		if err := l.check(pay, l.next()); !errors.Is(err, ErrInsufficientBalance) {
+			t.Errorf("height %d: spend %v: err = %v, want %v", tt.height, pay.Value, err, ErrInsufficientBalance)
+		}
+		pay.Value = tt.spendable
+		if err := l.check(pay, l.next()); err != nil {
+			t.Errorf("height %d: spend %v: %v", tt.height, pay.Value, err)
+		}
+
+		b := &Block{Transactions: []*Transaction{NewTransaction(MINING_SENDER, miner.BlockchainAddress(), 1)}}
+		chain = append(chain, b)
+		l.apply(b)
+	}
+}
+
+func TestCoinbaseMaturityZero(t *testing.T) {
+	miner, err := NewWallet(schemeOf(t, common.SCHEME_ECDSA_P256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := &ChainParams{BlockReward: 1}
+	l := newLedger(params, false)
+	l.apply(params.GenesisBlock())
+	l.apply(&Block{Transactions: []*Transaction{NewTransaction(MINING_SENDER, miner.BlockchainAddress(), 1)}})
+	if got := l.spendable(miner.BlockchainAddress()); got != 1 {
+		t.Errorf("spendable = %v, want 1: a reward is mature at once without a maturity", got)
+	}
+}
+
+// A slash burns the stake of the offender and moves no coins. The offender can be slashed only once,
+// and can't stake again.
+func TestSlash(t *testing.T) {
+	offender, err := NewWallet(schemeOf(t, common.SCHEME_ECDSA_P256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter, err := NewWallet(schemeOf(t, common.SCHEME_ECDSA_P256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := &ChainParams{Premine: []*Allocation{{BlockchainAddress: offender.BlockchainAddress(), Value: 10}}}
+	l := newLedger(params, true)
+	l.apply(params.GenesisBlock())
+	l.apply(&Block{Transactions: []*Transaction{NewStakeTransaction(offender.BlockchainAddress(), common.TRANSACTION_TYPE_STAKE, 4)}})
+
+	slash := func(value float64, recipient string) *Transaction {
+		s := NewSlashTransaction(offender.BlockchainAddress(), &SlashEvidence{})
+		s.Value = value
+		s.RecipientBlockchainAddress = recipient
+		return s
+	}
+	tests := []struct {
+		name string
+		t    *Transaction
+		want error
+	}{
+		{"value", slash(4, ""), ErrInvalidTransaction},
+		{"recipient", slash(0, reporter.BlockchainAddress()), ErrInvalidTransaction},
+		{"evidence", NewSlashTransaction(offender.BlockchainAddress(), nil), ErrInvalidTransaction},
+		{"slash", slash(0, ""), nil},
+	}
+	for _, tt := range tests {
+		if err := l.check(tt.t, l.next()); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	p := l.next()
+	if err := l.check(slash(0, ""), p); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.check(slash(0, ""), p); !errors.Is(err, ErrAlreadySlashed) {
+		t.Errorf("second slash in a block: err = %v, want %v", err, ErrAlreadySlashed)
+	}
+
+	l.apply(&Block{Transactions: []*Transaction{slash(0, "")}})
+	if got := l.spendable(offender.BlockchainAddress()); got != 6 {
+		t.Errorf("spendable = %v, want 6: a slash only burns the stake", got)
+	}
+	if l.stakes.Staked[offender.BlockchainAddress()] != 0 || l.stakes.Burned != 4 {
+		t.Errorf("staked = %v, burned = %v, want 0 and 4", l.stakes.Staked[offender.BlockchainAddress()], l.stakes.Burned)
+	}
+	if err := l.check(slash(0, ""), l.next()); !errors.Is(err, ErrAlreadySlashed) {
+		t.Errorf("slash in a later block: err = %v, want %v", err, ErrAlreadySlashed)
+	}
+	stake := NewStakeTransaction(offender.BlockchainAddress(), common.TRANSACTION_TYPE_STAKE, 1)
+	stake.Nonce = l.nonces[offender.BlockchainAddress()]
+	if err := l.check(stake, l.next()); !errors.Is(err, ErrAlreadySlashed) {
+		t.Errorf("stake after the slash: err = %v, want %v", err, ErrAlreadySlashed)
+	}
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// MerkleRoot is the root of the binary Merkle tree whose leaves are the digests of the transactions.
+// A level with an odd number of nodes pairs the last node with itself, as in Bitcoin.
+// The root of no transactions is 32 zero bytes.
+func MerkleRoot(transactions []*Transaction) string {
+	if len(transactions) == 0 {
+		return hex.EncodeToString(make([]byte, sha256.Size))
+	}
+	level := make([][]byte, 0, len(transactions))
+	for _, t := range transactions {
+		level = append(level, t.Digest())
+	}
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][]byte, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			h := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+			next = append(next, h[:])
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0])
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+// 署名されたmessageの検証。public_keyがblockchain_addressの鍵であることも確認する。
+type MessageVerifyRequest struct {
+	BlockchainAddress string `json:"blockchain_address"`
+	PublicKey         string `json:"public_key"`
+	Message           string `json:"message"`
+	Signature         string `json:"signature"`
+	SignatureScheme   string `json:"signature_scheme"` // empty means the scheme of blockchain_address
+}
+
+func (r MessageVerifyRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.BlockchainAddress, validation.Required, validation.Length(26, 35)),
+		validation.Field(&r.PublicKey, validation.Required),
+		validation.Field(&r.Message, validation.By(validateMessage)),
+		validation.Field(&r.Signature, validation.Required),
+		validation.Field(&r.SignatureScheme, validation.In(common.SignatureSchemes...)),
+	)
+}
+
+type MessageVerifyResponse struct {
+	Valid bool `json:"valid"`
+	// Reason tells why the signature is not valid.
+	Reason string `json:"reason,omitempty"`
+}
+
+func validateMessage(v interface{}) error {
+	s, _ := v.(string)
+	return common.ValidateMessage(s)
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	MINING_RESULT_SUCCESS      = "success"
+	MINING_RESULT_EMPTY_POOL   = "empty_pool"
+	MINING_RESULT_NOT_PRODUCER = "not_producer"
+	MINING_RESULT_SEAL_FAILED  = "seal_failed"
+	MINING_RESULT_STALE        = "stale"
+
+	REJECT_REASON_MALFORMED            = "malformed"
+	REJECT_REASON_INVALID_SIGNATURE    = "invalid_signature"
+	REJECT_REASON_INSUFFICIENT_BALANCE = "insufficient_balance"
+)
+
+var (
+	miningAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "blockchain_mining_attempts_total",
+		Help: "Number of mining attempts by result.",
+	}, []string{"result"})
+	hashRate = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "blockchain_hash_rate",
+		Help: "Hashes per second measured during the last proof of work.",
+	})
+	blockInterval = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "blockchain_block_interval_seconds",
+		Help:    "Seconds between a block and its parent.",
+		Buckets: []float64{1, 5, 10, 20, 30, 60, 120, 300, 600},
+	})
+	transactionsAccepted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "blockchain_transactions_accepted_total",
+		Help: "Number of transactions added to the pool.",
+	})
+	transactionsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "blockchain_transactions_rejected_total",
+		Help: "Number of rejected transactions by reason.",
+	}, []string{"reason"})
+	signatureVerifySeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "blockchain_signature_verify_seconds",
+		Help:    "Seconds to verify the signature of a transaction by signature scheme.",
+		Buckets: []float64{0.00001, 0.00005, 0.0001, 0.0002, 0.0005, 0.001, 0.005, 0.01},
+	}, []string{"scheme"})
+)
+
+func CountRejectedTransaction(reason string) {
+	transactionsRejected.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+const (
+	DEFAULT_GENESIS_TIMESTAMP = 1668366000000000000 // 2022-11-13T19:00:00Z
+	DEFAULT_HALVING_INTERVAL  = 210                 // blocks
+	DEFAULT_MAX_SUPPLY        = 420.0               // MINING_REWARD halving every DEFAULT_HALVING_INTERVAL blocks converges to 419, as the genesis block pays none. The rest is room for a premine
+	MAX_HALVINGS              = 64                  // the reward is 0 after this many halvings, as in Bitcoin
+	REWARD_PRECISION          = 1e8                 // the last reward before MaxSupply is rounded to 8 decimals, like satoshis
+	DEFAULT_COINBASE_MATURITY = 10                  // blocks
+)
+
+var (
+	ErrGenesisMismatch = errors.New("genesis block does not match")
+	ErrNetworkMismatch = errors.New("address_version is not the one of the network")
+)
+
+// ChainParams are the rules every node of a network must share. They are loaded from the -genesis file.
+// The genesis block commits to all of them, so two nodes with different params have different genesis hashes.
+type ChainParams struct {
+	ChainID          string        `json:"chain_id"`
+	GenesisTimestamp int64         `json:"genesis_timestamp"` // UNIX time in ns
+	Premine          []*Allocation `json:"premine"`
+	Difficulty       int           `json:"difficulty"`               // leading zeros of the hash of pow blocks
+	BlockReward      float64       `json:"block_reward"`             // paid to the producer of each block until the first halving
+	HalvingInterval  int           `json:"halving_interval"`         // blocks between halvings of the reward. 0 never halves
+	MaxSupply        float64       `json:"max_supply"`               // cap of the premine and all rewards. 0 has no cap
+	CoinbaseMaturity int           `json:"coinbase_maturity"`        // the reward of the block at height h can be spent from height h + CoinbaseMaturity
+	AddressVersion   byte          `json:"address_version"`          // version byte of P-256 addresses, see common.SetAddressVersion
+	GenesisStakes    []*Allocation `json:"genesis_stakes,omitempty"` // stakes of pos at the genesis, which can't be unstaked. Omitted when empty, so the other genesis hashes stay the same
+
+	network *common.Network
+}
+
+// Allocation is coins the genesis block gives to an address.
+type Allocation struct {
+	BlockchainAddress string  `json:"blockchain_address"`
+	Value             float64 `json:"value"`
+}
+
+// DefaultChainParams are the params of a node of network started without -genesis.
+// Each network has its own chain ID, so its genesis block differs from the others.
+func DefaultChainParams(network *common.Network) *ChainParams {
+	return &ChainParams{
+		ChainID:          network.Name,
+		GenesisTimestamp: DEFAULT_GENESIS_TIMESTAMP,
+		Premine:          []*Allocation{},
+		Difficulty:       network.Difficulty,
+		BlockReward:      MINING_REWARD,
+		HalvingInterval:  DEFAULT_HALVING_INTERVAL,
+		MaxSupply:        DEFAULT_MAX_SUPPLY,
+		CoinbaseMaturity: DEFAULT_COINBASE_MATURITY,
+		AddressVersion:   network.AddressVersion,
+		network:          network,
+	}
+}
+
+// LoadChainParams reads a params file of network. Fields missing in the file keep the defaults of network.
+// It switches the address version of the process, so that the premine addresses are checked on the right network.
+func LoadChainParams(network *common.Network, path string) (*ChainParams, error) {
+	p := DefaultChainParams(network)
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, p); err != nil {
+			return nil, fmt.Errorf("genesis %s: %w", path, err)
+		}
+	}
+	if p.AddressVersion != network.AddressVersion {
+		return nil, fmt.Errorf("genesis %s: %w %s (%#02x)", path, ErrNetworkMismatch, network.Name, network.AddressVersion)
+	}
+	network.Use()
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("genesis %s: %w", path, err)
+	}
+	return p, nil
+}
+
+func (p ChainParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ChainID, validation.Required),
+		validation.Field(&p.GenesisTimestamp, validation.Required, validation.Min(int64(0))),
+		validation.Field(&p.Premine),
+		validation.Field(&p.GenesisStakes),
+		validation.Field(&p.Difficulty, validation.Min(1), validation.Max(64)),
+		validation.Field(&p.BlockReward, validation.Min(0.0)),
+		validation.Field(&p.HalvingInterval, validation.Min(0)),
+		validation.Field(&p.CoinbaseMaturity, validation.Min(0)),
+		validation.Field(&p.MaxSupply, validation.Min(0.0), validation.When(p.MaxSupply > 0, validation.Min(p.premineTotal()))),
+	)
+}
+
+func (p *ChainParams) Network() *common.Network {
+	return p.network
+}
+
+func (p *ChainParams) premineTotal() float64 {
+	total := 0.0
+	for _, a := range p.Premine {
+		total += a.Value
+	}
+	return total
+}
+
+// Reward is the reward of the block at height, given the coins issued before it.
+// It halves every HalvingInterval blocks, and it is cut so that the issued coins never exceed MaxSupply.
+func (p *ChainParams) Reward(height int, issued float64) float64 {
+	reward := p.BlockReward
+	if p.HalvingInterval > 0 {
+		halvings := height / p.HalvingInterval
+		if halvings >= MAX_HALVINGS {
+			return 0
+		}
+		reward = math.Ldexp(reward, -halvings)
+	}
+	if p.MaxSupply > 0 && issued+reward > p.MaxSupply {
+		reward = math.Max(math.Round((p.MaxSupply-issued)*REWARD_PRECISION)/REWARD_PRECISION, 0)
+	}
+	return reward
+}
+
+// NextHalving is the first height after height where the reward halves, or 0 if it never does.
+func (p *ChainParams) NextHalving(height int) int {
+	if p.HalvingInterval == 0 || height/p.HalvingInterval >= MAX_HALVINGS {
+		return 0
+	}
+	return (height/p.HalvingInterval + 1) * p.HalvingInterval
+}
+
+func (a Allocation) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.BlockchainAddress, validation.Required, validation.By(validateAddress)),
+		validation.Field(&a.Value, validation.Required, validation.Min(0.0)),
+	)
+}
+
+// Hash is the SHA-256 of the params, which the genesis block takes as its previous hash.
+func (p *ChainParams) Hash() string {
+	m, _ := json.Marshal(p)
+	return fmt.Sprintf("%x", sha256.Sum256(m))
+}
+
+// GenesisBlock pays the premine from MINING_SENDER. It has no producer, and every node creates the same one.
+func (p *ChainParams) GenesisBlock() *Block {
+	transactions := make([]*Transaction, 0, len(p.Premine))
+	for _, a := range p.Premine {
+		transactions = append(transactions, NewTransaction(MINING_SENDER, a.BlockchainAddress, a.Value))
+	}
+	return &Block{
+		Timestamp:    p.GenesisTimestamp,
+		PreviousHash: p.Hash(),
+		MerkleRoot:   MerkleRoot(transactions),
+		Difficulty:   p.Difficulty,
+		Transactions: transactions,
+	}
+}
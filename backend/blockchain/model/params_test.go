@@ -0,0 +1,140 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+func TestReward(t *testing.T) {
+	p := &ChainParams{BlockReward: 1, HalvingInterval: 10, MaxSupply: 100}
+	tests := []struct {
+		name   string
+		height int
+		issued float64
+		want   float64
+	}{
+		{"first block", 1, 0, 1},
+		{"before the first halving", 9, 8, 1},
+		{"first halving", 10, 9, 0.5},
+		{"second halving", 25, 10, 0.25},
+		{"last halving", 10 * (MAX_HALVINGS - 1), 10, 1.0 / (1 << (MAX_HALVINGS - 1))},
+		{"after the last halving", 10 * MAX_HALVINGS, 10, 0},
+		// the last reward is cut to what is left under the max supply, rounded to 8 decimals
+		{"cut by the max supply", 1, 99.3, 0.7},
+		{"cut to 8 decimals", 10, 99.999999994, 0.00000001},
+		{"max supply reached", 1, 100, 0},
+	}
+	for _, tt := range tests {
+		if got := p.Reward(tt.height, tt.issued); got != tt.want {
+			t.Errorf("%s: Reward(%d, %v) = %v, want %v", tt.name, tt.height, tt.issued, got, tt.want)
+		}
+	}
+
+	never := &ChainParams{BlockReward: 1}
+	if got := never.Reward(10*MAX_HALVINGS, 1e9); got != 1 {
+		t.Errorf("no halving and no cap: Reward = %v, want 1", got)
+	}
+}
+
+// The default schedule never issues more than DEFAULT_MAX_SUPPLY: with a premine, the last reward is cut
+// so that the supply ends exactly at the cap.
+func TestRewardSchedule(t *testing.T) {
+	p := DefaultChainParams(&common.Network{Name: common.NETWORK_REGTEST})
+	tests := []struct {
+		premine float64
+		want    float64
+	}{
+		// the genesis block pays no reward, so the rewards alone stop one block reward short of the cap
+		{0, p.MaxSupply - p.BlockReward},
+		{10, p.MaxSupply},
+	}
+	for _, tt := range tests {
+		issued := tt.premine
+		for height := 1; ; height++ {
+			reward := p.Reward(height, issued)
+			if reward == 0 {
+				break
+			}
+			issued += reward
+		}
+		if issued > p.MaxSupply+1e-8 {
+			t.Errorf("premine %v: issued %v, more than the max supply %v", tt.premine, issued, p.MaxSupply)
+		}
+		if math.Abs(issued-tt.want) > 1e-6 {
+			t.Errorf("premine %v: issued %v, want %v", tt.premine, issued, tt.want)
+		}
+	}
+}
+
+func TestNextHalving(t *testing.T) {
+	p := &ChainParams{BlockReward: 1, HalvingInterval: 10}
+	tests := []struct {
+		height int
+		want   int
+	}{
+		{0, 10},
+		{9, 10},
+		{10, 20},
+		{10*MAX_HALVINGS - 1, 10 * MAX_HALVINGS},
+		{10 * MAX_HALVINGS, 0},
+	}
+	for _, tt := range tests {
+		if got := p.NextHalving(tt.height); got != tt.want {
+			t.Errorf("NextHalving(%d) = %d, want %d", tt.height, got, tt.want)
+		}
+	}
+	if got := (&ChainParams{BlockReward: 1}).NextHalving(5); got != 0 {
+		t.Errorf("NextHalving without halving = %d, want 0", got)
+	}
+}
+
+func TestVerifyReward(t *testing.T) {
+	producer, err := NewWallet(schemeOf(t, common.SCHEME_ECDSA_P256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewWallet(schemeOf(t, common.SCHEME_ECDSA_P256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := func(transactions ...*Transaction) *Block {
+		return &Block{Producer: producer.PublicKeyStr(), Transactions: transactions}
+	}
+	reward := func(recipient string, value float64) *Transaction {
+		return NewTransaction(MINING_SENDER, recipient, value)
+	}
+	tests := []struct {
+		name   string
+		block  *Block
+		reward float64
+		valid  bool
+	}{
+		{"reward", block(reward(producer.BlockchainAddress(), 0.5)), 0.5, true},
+		{"no reward", block(), 0.5, true},
+		{"wrong value", block(reward(producer.BlockchainAddress(), 1)), 0.5, false},
+		{"after the max supply", block(reward(producer.BlockchainAddress(), 0)), 0, false},
+		{"two rewards", block(reward(producer.BlockchainAddress(), 0.5), reward(producer.BlockchainAddress(), 0.5)), 0.5, false},
+		{"not to the producer", block(reward(other.BlockchainAddress(), 0.5)), 0.5, false},
+	}
+	for _, tt := range tests {
+		err := verifyReward(tt.block, tt.reward)
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidSeal) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, ErrInvalidSeal)
+		}
+	}
+}
+
+func schemeOf(t *testing.T, id string) common.SignatureScheme {
+	t.Helper()
+	scheme, err := common.SchemeByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
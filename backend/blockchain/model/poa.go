@@ -0,0 +1,346 @@
+package model
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+const (
+	POA_PERIOD_SEC                = 5   // length of the slot of a validator.
+	POA_SNAPSHOT_INTERVAL         = 64  // blocks between the validator sets kept to resume from.
+	VALIDATOR_REQUEST_MAX_AGE_SEC = 300 // how old the timestamp of a signed vote request may be.
+)
+
+var (
+	ErrNoValidators     = errors.New("poa needs at least one validator")
+	ErrNotValidator     = errors.New("this node is not a validator")
+	ErrUnknownValidator = errors.New("block is signed by an unknown validator")
+	ErrOutOfTurn        = errors.New("block is produced out of turn")
+	ErrNotProposable    = errors.New("the vote does not change the validator set")
+	ErrUnauthorized     = errors.New("the request is not signed by the key of this node")
+)
+
+// ValidatorVote is carried by a block to add or remove a validator.
+// The change applies when more than half of the validators have voted the same way.
+type ValidatorVote struct {
+	PublicKey string `json:"public_key"`
+	Authorize bool   `json:"authorize"`
+}
+
+// ProofOfAuthority lets a fixed set of validators take turns.
+// Time is divided into slots of period, and slot n belongs to validators[n % len(validators)].
+// The producer signs the block, and the signature is verified against the validator set at the parent.
+type ProofOfAuthority struct {
+	genesis    []string
+	period     time.Duration
+	clock      Clock
+	privateKey crypto.Signer
+	publicKey  string
+
+	mux         sync.Mutex
+	proposals   map[string]bool // votes this node puts into the blocks it produces
+	lastRequest int64           // timestamp of the last signed request, so that a request can't be replayed
+
+	muxSnapshots sync.Mutex
+	snapshots    map[string]*validatorSnapshot // by the hash of the block they are the state after
+}
+
+// validatorSnapshot is the validator set and the open votes after a block.
+type validatorSnapshot struct {
+	validators []string
+	tally      map[string]map[string]bool // candidate -> voter -> authorize
+}
+
+// NewProofOfAuthority creates the engine of a node signing with privateKey.
+// The node produces blocks only while its public key is in the validator set.
+func NewProofOfAuthority(validators []string, privateKey crypto.Signer, period time.Duration, clock Clock) (*ProofOfAuthority, error) {
+	if len(validators) == 0 {
+		return nil, ErrNoValidators
+	}
+	genesis := make([]string, 0, len(validators))
+	for _, v := range validators {
+		publicKey, err := canonicalPublicKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("validator %s: %w", v, err)
+		}
+		genesis = appendValidator(genesis, publicKey)
+	}
+	return &ProofOfAuthority{
+		genesis:    genesis,
+		period:     period,
+		clock:      clock,
+		privateKey: privateKey,
+		publicKey:  encodePublicKey(privateKey.Public()),
+		proposals:  make(map[string]bool),
+		snapshots:  make(map[string]*validatorSnapshot),
+	}, nil
+}
+
+func (p *ProofOfAuthority) Name() string {
+	return CONSENSUS_POA
+}
+
+func (p *ProofOfAuthority) Interval() time.Duration {
+	return p.period
+}
+
+func (p *ProofOfAuthority) PublicKey() string {
+	return p.publicKey
+}
+
+func (p *ProofOfAuthority) CanProduce(chain []*Block) bool {
+	return p.checkTurn(chain, p.publicKey, p.clock.Now().UnixNano()) == nil
+}
+
+func (p *ProofOfAuthority) Seal(chain []*Block, b *Block) error {
+	if err := p.checkTurn(chain, p.publicKey, b.Timestamp); err != nil {
+		return err
+	}
+	b.Vote = p.nextVote(p.Validators(chain))
+	return signBlock(b, p.privateKey)
+}
+
+func (p *ProofOfAuthority) VerifySeal(chain []*Block, b *Block) error {
+	if err := verifyProducerSignature(b); err != nil {
+		return err
+	}
+	if b.Vote != nil {
+		if _, _, err := common.ParseAnyPublicKey(b.Vote.PublicKey); err != nil {
+			return fmt.Errorf("%w: vote must be for a public key in the encoding of its scheme", ErrInvalidSeal)
+		}
+	}
+	if slot := p.slot(b.Timestamp); slot > p.slot(p.clock.Now().UnixNano()) {
+		return fmt.Errorf("%w: slot %d is in the future", ErrOutOfTurn, slot)
+	}
+	return p.checkTurn(chain, b.Producer, b.Timestamp)
+}
+
+// ForkChoice follows the longest chain. Blocks out of turn are rejected, so a longer chain had more validators online.
+func (p *ProofOfAuthority) ForkChoice(current, candidate []*Block) bool {
+	return len(candidate) > len(current)
+}
+
+// checkTurn checks that producer owns the slot of timestamp and that the parent is in an earlier slot.
+func (p *ProofOfAuthority) checkTurn(chain []*Block, producer string, timestamp int64) error {
+	validators := p.Validators(chain)
+	if !containsValidator(validators, producer) {
+		return ErrUnknownValidator
+	}
+	slot := p.slot(timestamp)
+	if validators[slot%int64(len(validators))] != producer {
+		return fmt.Errorf("%w: slot %d", ErrOutOfTurn, slot)
+	}
+	// the genesis block is not produced in a slot, so its timestamp is not in the schedule.
+	if len(chain) > 1 && slot <= p.slot(chain[len(chain)-1].Timestamp) {
+		return fmt.Errorf("%w: slot %d already has a block", ErrOutOfTurn, slot)
+	}
+	return nil
+}
+
+func (p *ProofOfAuthority) slot(timestamp int64) int64 {
+	return timestamp / p.period.Nanoseconds()
+}
+
+// Validators is the validator set after the votes of chain, sorted, which is the order of the turns.
+// The set is kept every POA_SNAPSHOT_INTERVAL blocks and at the tip, so only the votes after
+// the last kept set are replayed, instead of the whole chain on every block.
+func (p *ProofOfAuthority) Validators(chain []*Block) []string {
+	p.muxSnapshots.Lock()
+	defer p.muxSnapshots.Unlock()
+
+	snap := &validatorSnapshot{validators: p.genesis, tally: map[string]map[string]bool{}}
+	start := 0
+	for i := len(chain) - 1; i >= 0; i-- {
+		if s, ok := p.snapshots[chain[i].Hash()]; ok {
+			snap, start = s, i+1
+			break
+		}
+	}
+	snap = snap.copy()
+	for i := start; i < len(chain); i++ {
+		snap.apply(chain[i])
+		if (i+1)%POA_SNAPSHOT_INTERVAL == 0 || i == len(chain)-1 {
+			p.snapshots[chain[i].Hash()] = snap.copy()
+		}
+	}
+	p.dropTip(chain)
+	return snap.validators
+}
+
+// dropTip forgets the sets kept at the tips before the one of chain, apart from the intervals.
+// Must be called with muxSnapshots held.
+func (p *ProofOfAuthority) dropTip(chain []*Block) {
+	if len(chain) < 2 || (len(chain)-1)%POA_SNAPSHOT_INTERVAL == 0 {
+		return
+	}
+	delete(p.snapshots, chain[len(chain)-2].Hash())
+}
+
+func (s *validatorSnapshot) copy() *validatorSnapshot {
+	c := &validatorSnapshot{
+		validators: append([]string{}, s.validators...),
+		tally:      make(map[string]map[string]bool, len(s.tally)),
+	}
+	for candidate, votes := range s.tally {
+		c.tally[candidate] = make(map[string]bool, len(votes))
+		for voter, authorize := range votes {
+			c.tally[candidate][voter] = authorize
+		}
+	}
+	return c
+}
+
+// apply counts the vote of b. The change applies when more than half of the validators agree.
+func (s *validatorSnapshot) apply(b *Block) {
+	v := b.Vote
+	if v == nil || !containsValidator(s.validators, b.Producer) {
+		return
+	}
+	if s.tally[v.PublicKey] == nil {
+		s.tally[v.PublicKey] = make(map[string]bool)
+	}
+	s.tally[v.PublicKey][b.Producer] = v.Authorize
+	agree := 0
+	for voter, authorize := range s.tally[v.PublicKey] {
+		if authorize == v.Authorize && containsValidator(s.validators, voter) {
+			agree++
+		}
+	}
+	if agree*2 <= len(s.validators) {
+		return
+	}
+	switch {
+	case v.Authorize:
+		s.validators = appendValidator(s.validators, v.PublicKey)
+	case len(s.validators) > 1:
+		s.validators = removeValidator(s.validators, v.PublicKey)
+		for _, votes := range s.tally {
+			delete(votes, v.PublicKey)
+		}
+	}
+	delete(s.tally, v.PublicKey)
+}
+
+// VerifyRequest checks that a request to change the votes of this node is signed with the key of the node.
+// timestamp must be recent and after the one of the last request, so that a request can't be replayed.
+func (p *ProofOfAuthority) VerifyRequest(message string, timestamp int64, signature string) error {
+	scheme, err := common.SchemeOfPublicKey(p.privateKey.Public())
+	if err != nil {
+		return err
+	}
+	sig, err := scheme.ParseSignature(signature)
+	if err != nil || !common.VerifyMessage(scheme, p.privateKey.Public(), message, sig) {
+		return ErrUnauthorized
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	now := p.clock.Now().UnixNano()
+	maxAge := (time.Second * VALIDATOR_REQUEST_MAX_AGE_SEC).Nanoseconds()
+	if timestamp <= p.lastRequest || timestamp < now-maxAge || timestamp > now+maxAge {
+		return fmt.Errorf("%w: the timestamp is too old or already used", ErrUnauthorized)
+	}
+	p.lastRequest = timestamp
+	return nil
+}
+
+// Propose makes this node vote for adding (authorize) or removing a validator in the blocks it produces.
+func (p *ProofOfAuthority) Propose(chain []*Block, publicKey string, authorize bool) error {
+	publicKey, err := canonicalPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	validators := p.Validators(chain)
+	if !containsValidator(validators, p.publicKey) {
+		return ErrNotValidator
+	}
+	if containsValidator(validators, publicKey) == authorize {
+		return ErrNotProposable
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.proposals[publicKey] = authorize
+	return nil
+}
+
+// Discard stops voting for publicKey.
+func (p *ProofOfAuthority) Discard(publicKey string) {
+	if k, err := canonicalPublicKey(publicKey); err == nil {
+		publicKey = k
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	delete(p.proposals, publicKey)
+}
+
+// Proposals returns the votes this node still casts, sorted by public key.
+func (p *ProofOfAuthority) Proposals() []*ValidatorVote {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	votes := make([]*ValidatorVote, 0, len(p.proposals))
+	for k, authorize := range p.proposals {
+		votes = append(votes, &ValidatorVote{PublicKey: k, Authorize: authorize})
+	}
+	sort.Slice(votes, func(i, j int) bool { return votes[i].PublicKey < votes[j].PublicKey })
+	return votes
+}
+
+// nextVote picks a proposal for the next block. Proposals that already passed are dropped.
+func (p *ProofOfAuthority) nextVote(validators []string) *ValidatorVote {
+	for _, v := range p.Proposals() {
+		if containsValidator(validators, v.PublicKey) == v.Authorize {
+			p.Discard(v.PublicKey)
+			continue
+		}
+		return v
+	}
+	return nil
+}
+
+func containsValidator(validators []string, publicKey string) bool {
+	i := sort.SearchStrings(validators, publicKey)
+	return i < len(validators) && validators[i] == publicKey
+}
+
+func appendValidator(validators []string, publicKey string) []string {
+	if containsValidator(validators, publicKey) {
+		return validators
+	}
+	validators = append(validators, publicKey)
+	sort.Strings(validators)
+	return validators
+}
+
+func removeValidator(validators []string, publicKey string) []string {
+	i := sort.SearchStrings(validators, publicKey)
+	if i == len(validators) || validators[i] != publicKey {
+		return validators
+	}
+	return append(validators[:i:i], validators[i+1:]...)
+}
+
+// canonicalPublicKey is the string of a validator: the encoding of EncodePublicKey of its scheme.
+// Keys not in that encoding, e.g. compressed, are read as P-256 keys like before the other schemes.
+func canonicalPublicKey(s string) (string, error) {
+	if _, _, err := common.ParseAnyPublicKey(s); err == nil {
+		return s, nil
+	}
+	pub, err := common.ParsePublicKey(s)
+	if err != nil {
+		return "", err
+	}
+	return common.PublicKeyString(pub), nil
+}
+
+func encodePublicKey(publicKey crypto.PublicKey) string {
+	scheme, err := common.SchemeOfPublicKey(publicKey)
+	if err != nil {
+		return ""
+	}
+	return scheme.EncodePublicKey(publicKey)
+}
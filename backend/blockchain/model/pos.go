@@ -0,0 +1,328 @@
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+const (
+	POS_PERIOD_SEC        = 5  // length of a slot.
+	POS_SNAPSHOT_INTERVAL = 64 // blocks between the stakes kept to resume from.
+)
+
+var (
+	ErrNoStake         = errors.New("the producer has no stake")
+	ErrNoGenesisStakes = errors.New("pos needs at least one of genesis_stakes in the chain params")
+	ErrInvalidEvidence = errors.New("invalid evidence")
+)
+
+// SlashEvidence is two different blocks at the same height signed by the same producer.
+type SlashEvidence struct {
+	First  *Block `json:"first"`
+	Second *Block `json:"second"`
+}
+
+// ProofOfStake picks the producer of each slot at random, weighted by stake.
+// The seed of a block is the hash of the signature of its producer over the seed of the parent and the slot.
+// Signatures are deterministic, so the producer has one seed per slot whatever it puts in the block,
+// and every node computes the same producer from the seed of the parent. The seed of the genesis block is its hash.
+// A producer can still skip its slot, but it can't try other contents of the block for a better seed.
+//
+// Stake comes from stake transactions. The genesis stakers start with the genesis_stakes of the chain params,
+// which can't be unstaked, so that the chain can start before anyone has coins.
+// The params are covered by the genesis hash, so every node of the chain starts with the same stakers.
+type ProofOfStake struct {
+	genesis    map[string]float64 // address -> stake
+	period     time.Duration
+	clock      Clock
+	privateKey crypto.Signer
+	address    string
+
+	muxSnapshots sync.Mutex
+	snapshots    map[string]*StakeLedger // by the hash of the block they are the state after
+}
+
+func NewProofOfStake(stakes []*Allocation, privateKey crypto.Signer, period time.Duration, clock Clock) (*ProofOfStake, error) {
+	genesis := make(map[string]float64, len(stakes))
+	for _, s := range stakes {
+		if s.Value > 0 {
+			genesis[s.BlockchainAddress] += s.Value
+		}
+	}
+	if len(genesis) == 0 {
+		return nil, ErrNoGenesisStakes
+	}
+	return &ProofOfStake{
+		genesis:    genesis,
+		period:     period,
+		clock:      clock,
+		privateKey: privateKey,
+		address:    AddressFromPublicKey(privateKey.Public()),
+		snapshots:  make(map[string]*StakeLedger),
+	}, nil
+}
+
+func (p *ProofOfStake) Name() string {
+	return CONSENSUS_POS
+}
+
+func (p *ProofOfStake) Interval() time.Duration {
+	return p.period
+}
+
+func (p *ProofOfStake) CanProduce(chain []*Block) bool {
+	return p.checkTurn(chain, p.address, p.clock.Now().UnixNano()) == nil
+}
+
+func (p *ProofOfStake) Seal(chain []*Block, b *Block) error {
+	if err := p.checkTurn(chain, p.address, b.Timestamp); err != nil {
+		return err
+	}
+	b.Nonce, b.Difficulty = 0, 0
+	b.Height = len(chain)
+	scheme, err := setProducer(b, p.privateKey)
+	if err != nil {
+		return err
+	}
+	b.Seed = hex.EncodeToString(scheme.Sign(p.privateKey, seedDigest(chain[len(chain)-1], p.slot(b.Timestamp))))
+	return signBlock(b, p.privateKey)
+}
+
+// VerifySeal checks the turn of the producer and its seed. The nonce and the difficulty are
+// always zero, so that nothing in the header can be varied for free.
+func (p *ProofOfStake) VerifySeal(chain []*Block, b *Block) error {
+	if b.Nonce != 0 || b.Difficulty != 0 {
+		return fmt.Errorf("%w: nonce %d and difficulty %d, want 0", ErrInvalidSeal, b.Nonce, b.Difficulty)
+	}
+	if err := verifyProducerSignature(b); err != nil {
+		return err
+	}
+	if err := p.verifySeed(chain[len(chain)-1], b); err != nil {
+		return err
+	}
+	if b.Height != len(chain) {
+		return fmt.Errorf("%w: height %d at %d", ErrInvalidSeal, b.Height, len(chain))
+	}
+	if slot := p.slot(b.Timestamp); slot > p.slot(p.clock.Now().UnixNano()) {
+		return fmt.Errorf("%w: slot %d is in the future", ErrOutOfTurn, slot)
+	}
+	if err := p.checkTurn(chain, b.ProducerAddress(), b.Timestamp); err != nil {
+		return err
+	}
+	for _, t := range b.Transactions {
+		if t.Type != common.TRANSACTION_TYPE_SLASH {
+			continue
+		}
+		offender, err := p.VerifyEvidence(t.Evidence)
+		if err != nil {
+			return err
+		}
+		if offender != t.SenderBlockchainAddress {
+			return fmt.Errorf("%w: slash of %s with evidence against %s", ErrInvalidEvidence, t.SenderBlockchainAddress, offender)
+		}
+	}
+	return nil
+}
+
+// ForkChoice follows the longest chain.
+func (p *ProofOfStake) ForkChoice(current, candidate []*Block) bool {
+	return len(candidate) > len(current)
+}
+
+// VerifyEvidence returns the address of the producer who signed both blocks.
+func (p *ProofOfStake) VerifyEvidence(e *SlashEvidence) (string, error) {
+	if e == nil || e.First == nil || e.Second == nil {
+		return "", fmt.Errorf("%w: two blocks are required", ErrInvalidEvidence)
+	}
+	if e.First.Height <= 0 || e.First.Height != e.Second.Height {
+		return "", fmt.Errorf("%w: the blocks are not at the same height", ErrInvalidEvidence)
+	}
+	if e.First.Producer != e.Second.Producer {
+		return "", fmt.Errorf("%w: the blocks have different producers", ErrInvalidEvidence)
+	}
+	if bytes.Equal(e.First.SealHash(), e.Second.SealHash()) {
+		return "", fmt.Errorf("%w: the blocks are the same", ErrInvalidEvidence)
+	}
+	for _, b := range []*Block{e.First, e.Second} {
+		if err := verifyProducerSignature(b); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidEvidence, err)
+		}
+	}
+	return e.First.ProducerAddress(), nil
+}
+
+// seedDigest is what the producer of slot signs to make the seed of its block on top of parent.
+func seedDigest(parent *Block, slot int64) []byte {
+	h := sha256.New()
+	h.Write(seedOf(parent))
+	_ = binary.Write(h, binary.BigEndian, slot)
+	return h.Sum(nil)
+}
+
+// seedOf is the seed of b: the hash of its Seed, or the hash of the genesis block.
+func seedOf(b *Block) []byte {
+	if b.Seed == "" {
+		seed, _ := hex.DecodeString(b.Hash())
+		return seed
+	}
+	sig, _ := hex.DecodeString(b.Seed)
+	seed := sha256.Sum256(sig)
+	return seed[:]
+}
+
+// verifySeed checks that Seed is the signature of the producer over the seed of parent and the slot of b.
+func (p *ProofOfStake) verifySeed(parent *Block, b *Block) error {
+	_, pub, err := common.ParseAnyPublicKey(b.Producer)
+	if err != nil {
+		return fmt.Errorf("%w: producer is not a public key in the encoding of its scheme", ErrInvalidSeal)
+	}
+	scheme, err := common.SchemeOfPublicKey(pub)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSeal, err)
+	}
+	sig, err := scheme.ParseSignature(b.Seed)
+	if err != nil || !scheme.Verify(pub, seedDigest(parent, p.slot(b.Timestamp)), sig) {
+		return fmt.Errorf("%w: seed is not the signature of the producer", ErrInvalidSeal)
+	}
+	return nil
+}
+
+// Weights returns the stake of every address that may produce blocks on top of chain.
+func (p *ProofOfStake) Weights(chain []*Block) map[string]float64 {
+	return p.weights(p.stakeLedger(chain))
+}
+
+// stakeLedger is NewStakeLedger(chain). The stakes are kept every POS_SNAPSHOT_INTERVAL blocks and at the tip,
+// as the validator sets of poa, so that ValidChain doesn't replay the whole chain for every block.
+// The returned ledger is a copy, which the caller may change.
+func (p *ProofOfStake) stakeLedger(chain []*Block) *StakeLedger {
+	p.muxSnapshots.Lock()
+	defer p.muxSnapshots.Unlock()
+
+	l := newStakeLedger()
+	start := 0
+	for i := len(chain) - 1; i >= 0; i-- {
+		if s, ok := p.snapshots[chain[i].Hash()]; ok {
+			l, start = s.copy(), i+1
+			break
+		}
+	}
+	for height := start; height < len(chain); height++ {
+		l.release(height)
+		for _, t := range chain[height].Transactions {
+			l.apply(height, t)
+		}
+		if (height+1)%POS_SNAPSHOT_INTERVAL == 0 || height == len(chain)-1 {
+			p.snapshots[chain[height].Hash()] = l.copy()
+		}
+	}
+	if len(chain) >= 2 && (len(chain)-1)%POS_SNAPSHOT_INTERVAL != 0 {
+		delete(p.snapshots, chain[len(chain)-2].Hash())
+	}
+	return l
+}
+
+func (p *ProofOfStake) weights(l *StakeLedger) map[string]float64 {
+	weights := make(map[string]float64, len(l.Staked)+len(p.genesis))
+	for address, stake := range p.genesis {
+		if !l.Slashed[address] {
+			weights[address] += stake
+		}
+	}
+	for address, stake := range l.Staked {
+		weights[address] += stake
+	}
+	return weights
+}
+
+// ProducerAt picks the producer of slot on top of chain.
+func (p *ProofOfStake) ProducerAt(chain []*Block, slot int64) string {
+	weights := p.Weights(chain)
+	addresses := make([]string, 0, len(weights))
+	total := 0.0
+	for address, w := range weights {
+		if w > 0 {
+			addresses = append(addresses, address)
+		}
+	}
+	if len(addresses) == 0 {
+		return ""
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		total += weights[address]
+	}
+	seed := sha256.New()
+	seed.Write(seedOf(chain[len(chain)-1]))
+	_ = binary.Write(seed, binary.BigEndian, slot)
+	r := float64(binary.BigEndian.Uint64(seed.Sum(nil))) / math.Pow(2, 64) * total
+	for _, address := range addresses {
+		r -= weights[address]
+		if r < 0 {
+			return address
+		}
+	}
+	return addresses[len(addresses)-1]
+}
+
+func (p *ProofOfStake) checkTurn(chain []*Block, producer string, timestamp int64) error {
+	if p.Weights(chain)[producer] <= 0 {
+		return ErrNoStake
+	}
+	slot := p.slot(timestamp)
+	if p.ProducerAt(chain, slot) != producer {
+		return fmt.Errorf("%w: slot %d", ErrOutOfTurn, slot)
+	}
+	// the genesis block is not produced in a slot, so its timestamp is not in the schedule.
+	if len(chain) > 1 && slot <= p.slot(chain[len(chain)-1].Timestamp) {
+		return fmt.Errorf("%w: slot %d already has a block", ErrOutOfTurn, slot)
+	}
+	return nil
+}
+
+func (p *ProofOfStake) slot(timestamp int64) int64 {
+	return timestamp / p.period.Nanoseconds()
+}
+
+func (p *ProofOfStake) stakeOf(l *StakeLedger, address string) *StakeResponse {
+	return &StakeResponse{
+		BlockchainAddress: address,
+		Staked:            l.Staked[address],
+		Weight:            p.weights(l)[address],
+		Unbonding:         append([]*Unbonding{}, l.Unbonding[address]...),
+		Slashed:           l.Slashed[address],
+	}
+}
+
+// Stakes returns the stake of every address with weight, stake or unbonding amounts, sorted by address.
+func (p *ProofOfStake) Stakes(chain []*Block) []*StakeResponse {
+	l := p.stakeLedger(chain)
+	seen := make(map[string]bool)
+	for address := range p.weights(l) {
+		seen[address] = true
+	}
+	for address := range l.Unbonding {
+		seen[address] = true
+	}
+	addresses := make([]string, 0, len(seen))
+	for address := range seen {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	stakes := make([]*StakeResponse, 0, len(addresses))
+	for _, address := range addresses {
+		stakes = append(stakes, p.stakeOf(l, address))
+	}
+	return stakes
+}
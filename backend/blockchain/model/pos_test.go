@@ -0,0 +1,49 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+// The stakes kept by ProofOfStake are the same as the ones replayed from the whole chain,
+// for every block that ValidChain checks, across the snapshot intervals and the unbonding periods.
+func TestStakeSnapshots(t *testing.T) {
+	producer, err := NewWallet(schemeOf(t, common.SCHEME_ECDSA_P256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	staker, err := NewWallet(schemeOf(t, common.SCHEME_ECDSA_P256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProofOfStake([]*Allocation{{BlockchainAddress: producer.BlockchainAddress(), Value: 1}}, producer.PrivateKey(), time.Second, &fakeClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain := []*Block{(&ChainParams{}).GenesisBlock()}
+	for height := 1; height <= 2*POS_SNAPSHOT_INTERVAL+STAKE_LOCKUP_BLOCKS; height++ {
+		if got, want := p.stakeLedger(chain), NewStakeLedger(chain); !reflect.DeepEqual(got, want) {
+			t.Fatalf("height %d: stakes %+v, want %+v", height, got, want)
+		}
+		var transactions []*Transaction
+		if height%5 == 0 {
+			transactions = append(transactions, NewStakeTransaction(staker.BlockchainAddress(), common.TRANSACTION_TYPE_STAKE, 2))
+		}
+		if height%7 == 0 {
+			transactions = append(transactions, NewStakeTransaction(staker.BlockchainAddress(), common.TRANSACTION_TYPE_UNSTAKE, 1))
+		}
+		chain = append(chain, &Block{Timestamp: int64(height), PreviousHash: chain[len(chain)-1].Hash(), Transactions: transactions})
+	}
+
+	// the caller may change the stakes it gets without changing the kept ones
+	l := p.stakeLedger(chain)
+	l.Staked[staker.BlockchainAddress()] += 100
+	l.release(len(chain) + STAKE_LOCKUP_BLOCKS)
+	if got, want := p.stakeLedger(chain), NewStakeLedger(chain); !reflect.DeepEqual(got, want) {
+		t.Errorf("stakes after a change of a copy %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"crypto"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProofOfWork is the consensus where the winner of the mining competition produces the block.
+// Anyone may produce a block, and the longest chain wins.
+// The miner is in the header that the nonce is found for, and signs the block after finding it,
+// so that the block can be attributed to it.
+type ProofOfWork struct {
+	difficulty int
+	privateKey crypto.Signer
+}
+
+func NewProofOfWork(difficulty int, privateKey crypto.Signer) *ProofOfWork {
+	return &ProofOfWork{difficulty: difficulty, privateKey: privateKey}
+}
+
+func (p *ProofOfWork) Name() string {
+	return CONSENSUS_POW
+}
+
+func (p *ProofOfWork) Interval() time.Duration {
+	return time.Second * MINING_TIME_SEC
+}
+
+func (p *ProofOfWork) CanProduce(chain []*Block) bool {
+	return true
+}
+
+// Seal finds the nonce with which the header of b, as it is stored, meets the difficulty. Then it signs the block.
+// TODO: 時間かかる
+func (p *ProofOfWork) Seal(chain []*Block, b *Block) error {
+	b.Difficulty = p.difficulty
+	b.Nonce = 0
+	if _, err := setProducer(b, p.privateKey); err != nil {
+		return err
+	}
+	start := time.Now()
+	for !p.validProof(b) {
+		b.Nonce += 1
+	}
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		hashRate.Set(float64(b.Nonce+1) / elapsed)
+	}
+	return signBlock(b, p.privateKey)
+}
+
+// VerifySeal recomputes the hash from the stored header.
+func (p *ProofOfWork) VerifySeal(chain []*Block, b *Block) error {
+	if b.Difficulty != p.difficulty {
+		return fmt.Errorf("%w: difficulty %d, want %d", ErrInvalidSeal, b.Difficulty, p.difficulty)
+	}
+	if !p.validProof(b) {
+		return fmt.Errorf("%w: hash %s does not meet difficulty %d", ErrInvalidSeal, b.Hash(), p.difficulty)
+	}
+	return verifyProducerSignature(b)
+}
+
+// ForkChoice follows the longest chain. Every block has the same difficulty,
+// so the longest chain is also the one with the most work.
+func (p *ProofOfWork) ForkChoice(current, candidate []*Block) bool {
+	return len(candidate) > len(current)
+}
+
+// validProof reports whether the hash of the header of b starts with difficulty zeros.
+func (p *ProofOfWork) validProof(b *Block) bool {
+	return strings.HasPrefix(b.Hash(), strings.Repeat("0", p.difficulty))
+}
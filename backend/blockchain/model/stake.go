@@ -0,0 +1,179 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+const (
+	STAKE_LOCKUP_BLOCKS = 10 // blocks until an unstaked amount can be spent again.
+)
+
+var (
+	ErrStakingDisabled   = errors.New("staking needs the pos consensus")
+	ErrInsufficientStake = errors.New("insufficient stake")
+	ErrNothingToSlash    = errors.New("the producer has no stake to slash")
+)
+
+// Unbonding is an unstaked amount waiting for the end of the lock-up period.
+// It can still be slashed until ReleaseHeight.
+type Unbonding struct {
+	Amount        float64 `json:"amount"`
+	ReleaseHeight int     `json:"release_height"`
+}
+
+// StakeResponse is the stake of an address. Weight includes the genesis stake.
+type StakeResponse struct {
+	BlockchainAddress string       `json:"blockchain_address"`
+	Staked            float64      `json:"staked"`
+	Weight            float64      `json:"weight"`
+	Unbonding         []*Unbonding `json:"unbonding"`
+	Slashed           bool         `json:"slashed"`
+}
+
+type StakesResponse struct {
+	Stakes []*StakeResponse `json:"stakes"`
+	Length int              `json:"length"`
+}
+
+// StakeLedger is the state of the stakes replayed from the transactions of a chain.
+type StakeLedger struct {
+	Staked    map[string]float64
+	Unbonding map[string][]*Unbonding
+	Released  map[string]float64 // unstaked amounts back in the balance
+	Slashed   map[string]bool
+	Burned    float64 // stake and unbonding amounts taken by slashing
+}
+
+func NewStakeLedger(chain []*Block) *StakeLedger {
+	l := newStakeLedger()
+	for height, b := range chain {
+		l.release(height)
+		for _, t := range b.Transactions {
+			l.apply(height, t)
+		}
+	}
+	return l
+}
+
+func newStakeLedger() *StakeLedger {
+	return &StakeLedger{
+		Staked:    make(map[string]float64),
+		Unbonding: make(map[string][]*Unbonding),
+		Released:  make(map[string]float64),
+		Slashed:   make(map[string]bool),
+	}
+}
+
+func (l *StakeLedger) apply(height int, t *Transaction) {
+	sender := t.SenderBlockchainAddress
+	switch t.Type {
+	case common.TRANSACTION_TYPE_STAKE:
+		l.Staked[sender] += t.Value
+	case common.TRANSACTION_TYPE_UNSTAKE:
+		l.Staked[sender] -= t.Value
+		if l.Staked[sender] <= 0 {
+			delete(l.Staked, sender)
+		}
+		l.Unbonding[sender] = append(l.Unbonding[sender], &Unbonding{
+			Amount:        t.Value,
+			ReleaseHeight: height + STAKE_LOCKUP_BLOCKS,
+		})
+	case common.TRANSACTION_TYPE_SLASH:
+		l.Slashed[sender] = true
+		l.Burned += l.Staked[sender]
+		for _, u := range l.Unbonding[sender] {
+			l.Burned += u.Amount
+		}
+		delete(l.Staked, sender)
+		delete(l.Unbonding, sender)
+	}
+}
+
+func (l *StakeLedger) copy() *StakeLedger {
+	c := newStakeLedger()
+	for address, v := range l.Staked {
+		c.Staked[address] = v
+	}
+	for address, entries := range l.Unbonding {
+		for _, u := range entries {
+			c.Unbonding[address] = append(c.Unbonding[address], &Unbonding{Amount: u.Amount, ReleaseHeight: u.ReleaseHeight})
+		}
+	}
+	for address, v := range l.Released {
+		c.Released[address] = v
+	}
+	for address, v := range l.Slashed {
+		c.Slashed[address] = v
+	}
+	c.Burned = l.Burned
+	return c
+}
+
+// release moves the amounts whose lock-up period is over at height to Released.
+func (l *StakeLedger) release(height int) {
+	for address, entries := range l.Unbonding {
+		locked := entries[:0]
+		for _, u := range entries {
+			if u.ReleaseHeight <= height {
+				l.Released[address] += u.Amount
+				continue
+			}
+			locked = append(locked, u)
+		}
+		if len(locked) == 0 {
+			delete(l.Unbonding, address)
+			continue
+		}
+		l.Unbonding[address] = locked
+	}
+}
+
+// CreateStakeTransaction adds a stake or unstake transaction signed by the sender.
+// Staking locks the balance, and unstaking needs the stake that is not being unstaked in the pool.
+func (bc *Blockchain) CreateStakeTransaction(sender, typ string, value float64, nonce uint64, s *TransactionSignature) (*Transaction, error) {
+	if _, ok := bc.consensus.(*ProofOfStake); !ok {
+		CountRejectedTransaction(REJECT_REASON_MALFORMED)
+		return nil, ErrStakingDisabled
+	}
+	if !(value > 0) {
+		CountRejectedTransaction(REJECT_REASON_MALFORMED)
+		return nil, fmt.Errorf("%s needs a positive value", typ)
+	}
+	t := NewStakeTransaction(sender, typ, value)
+	t.Nonce = nonce
+	if !bc.VerifyTransactionSignature(s, t) {
+		CountRejectedTransaction(REJECT_REASON_INVALID_SIGNATURE)
+		return nil, ErrInvalidSignature
+	}
+	t.sign(s)
+	return t, bc.addToPool(t)
+}
+
+// SubmitEvidence adds a slash transaction for a producer who signed two blocks at the same height.
+// The evidence proves itself with the signatures of the producer, so the transaction is not signed.
+func (bc *Blockchain) SubmitEvidence(e *SlashEvidence) (*Transaction, error) {
+	pos, ok := bc.consensus.(*ProofOfStake)
+	if !ok {
+		return nil, ErrStakingDisabled
+	}
+	offender, err := pos.VerifyEvidence(e)
+	if err != nil {
+		return nil, err
+	}
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+	l := pos.stakeLedger(bc.Chain)
+	if pos.weights(l)[offender] <= 0 && len(l.Unbonding[offender]) == 0 {
+		return nil, ErrNothingToSlash
+	}
+	for _, p := range bc.transactionPool {
+		if p.SenderBlockchainAddress == offender && p.Type == common.TRANSACTION_TYPE_SLASH {
+			return p, nil
+		}
+	}
+	t := NewSlashTransaction(offender, e)
+	return t, bc.appendToPool(t)
+}
@@ -0,0 +1,61 @@
+package model
+
+// SupplyResponse is the supply at the tip of the chain.
+// Circulating is what can be spent: the issued coins minus the locked stake and the burned ones.
+type SupplyResponse struct {
+	CirculatingSupply float64 `json:"circulating_supply"`
+	TotalIssued       float64 `json:"total_issued"`
+	MaxSupply         float64 `json:"max_supply"`
+	Height            int     `json:"height"`       // height of the next block
+	BlockReward       float64 `json:"block_reward"` // reward of the next block
+	NextHalvingHeight int     `json:"next_halving_height"`
+}
+
+// issuedBy is what a block creates: the premine of the genesis block, or the reward of another block.
+func issuedBy(b *Block) float64 {
+	issued := 0.0
+	for _, t := range b.Transactions {
+		if t.SenderBlockchainAddress == MINING_SENDER {
+			issued += t.Value
+		}
+	}
+	return issued
+}
+
+func issued(chain []*Block) float64 {
+	total := 0.0
+	for _, b := range chain {
+		total += issuedBy(b)
+	}
+	return total
+}
+
+// nextReward is the reward of the block on top of chain.
+func (bc *Blockchain) nextReward(chain []*Block) float64 {
+	return bc.params.Reward(len(chain), issued(chain))
+}
+
+func (bc *Blockchain) Supply() *SupplyResponse {
+	bc.mux.Lock()
+	chain := bc.Chain
+	bc.mux.Unlock()
+	total := issued(chain)
+	l := NewStakeLedger(chain)
+	locked := l.Burned
+	for _, s := range l.Staked {
+		locked += s
+	}
+	for _, entries := range l.Unbonding {
+		for _, u := range entries {
+			locked += u.Amount
+		}
+	}
+	return &SupplyResponse{
+		CirculatingSupply: total - locked,
+		TotalIssued:       total,
+		MaxSupply:         bc.params.MaxSupply,
+		Height:            len(chain),
+		BlockReward:       bc.params.Reward(len(chain), total),
+		NextHalvingHeight: bc.params.NextHalving(len(chain)),
+	}
+}
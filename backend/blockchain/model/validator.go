@@ -0,0 +1,32 @@
+package model
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// ValidatorVoteRequest makes a validator vote for adding (authorize=true) or removing a validator.
+// Signature is the signature of common.ValidatorVoteMessage with the key of the node.
+type ValidatorVoteRequest struct {
+	PublicKey string `json:"public_key"`
+	Authorize bool   `json:"authorize"`
+	Timestamp int64  `json:"timestamp"` // UNIX time in ns
+	Signature string `json:"signature"`
+}
+
+func (r ValidatorVoteRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.PublicKey, validation.Required), // see common.ParseAnyPublicKey
+		validation.Field(&r.Timestamp, validation.Required),
+		validation.Field(&r.Signature, validation.Required),
+	)
+}
+
+// ValidatorsResponse is the validator set at the tip of the chain.
+// Proposals are the votes this node puts into the blocks it produces.
+type ValidatorsResponse struct {
+	Validators []string         `json:"validators"`
+	Length     int              `json:"length"`
+	Self       string           `json:"self"`
+	Validator  bool             `json:"validator"` // whether this node is in the set
+	Proposals  []*ValidatorVote `json:"proposals"`
+}
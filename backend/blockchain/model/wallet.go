@@ -1,83 +1,77 @@
 package model
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
-	"crypto/sha256"
+	"crypto"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
 
-	"github.com/btcsuite/btcd/btcutil/base58"
-	"golang.org/x/crypto/ripemd160"
+	"github.com/yagikota/blockchain_with_go/backend/common"
 )
 
+// Wallet is the key of the node. It receives the rewards and signs the blocks the node produces,
+// with a key of any signature scheme.
 type Wallet struct {
-	privateKey        *ecdsa.PrivateKey
-	publicKey         *ecdsa.PublicKey
+	privateKey        crypto.Signer
+	scheme            common.SignatureScheme
 	blockchainAddress string
 }
 
+// NewWallet creates a new key of scheme.
 // https://en.bitcoin.it/wiki/Technical_background_of_version_1_Bitcoin_addresses
-func NewWallet() *Wallet {
-	// 1. create ECDSA private key (32 bytes), public key (64 bytes).
-	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+func NewWallet(scheme common.SignatureScheme) (*Wallet, error) {
+	privateKey, err := scheme.GenerateKey()
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	w := &Wallet{
-		privateKey: privateKey,
-		publicKey:  &privateKey.PublicKey,
+	return newWallet(scheme, privateKey), nil
+}
+
+// NewWalletFromPrivateKey restores the wallet of the node from the hex of a private key of scheme,
+// so that a validator keeps its public key across restarts.
+func NewWalletFromPrivateKey(scheme common.SignatureScheme, s string) (*Wallet, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, common.ErrInvalidPrivateKey
 	}
+	privateKey, err := scheme.PrivateKeyFromBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	return newWallet(scheme, privateKey), nil
+}
 
-	// create blockchainAddress from publicKey
-	// 2.Perform SHA-256 hashing on the public key (32 bytes).
-	h2 := sha256.New()
-	h2.Write(w.publicKey.X.Bytes())
-	h2.Write(w.publicKey.Y.Bytes())
-	digest2 := h2.Sum(nil)
-	// 3. Perform RIPEMD-160 hashing on the result of SHA-256 (20 bytes)
-	h3 := ripemd160.New()
-	h3.Write(digest2)
-	digest3 := h3.Sum(nil) // digest3(20bytes)
-	// 4. Add network ID byte in front of RIPEMD-160 hash (0x00 for Main Network).
-	digest4 := make([]byte, len(digest3)+1)
-	digest4[0] = 0x00
-	copy(digest4[1:], digest3)
-	// 5. Perform SHA-256 hash on the extended RIPEMD-160 result.
-	h5 := sha256.New()
-	h5.Write(digest4)
-	digest5 := h5.Sum(nil)
-	// 6. Perform SHA-256 hash on the result of the previous SHA-256 hash.
-	h6 := sha256.New()
-	h6.Write(digest5)
-	digest6 := h6.Sum(nil)
-	// 7. Take the first 4 bytes of the second SHA-256 hash for checksum.
-	checkSum := digest6[:4]
-	// 8. Add the 4 checksum bytes from 7 at the end of extended RIPEMD-160 hash from 4 (25 bytes).
-	digestCheckSum := make([]byte, len(digest4)+len(checkSum))
-	copy(digestCheckSum[:len(digest4)], digest4)
-	copy(digestCheckSum[len(digest4):], checkSum)
-	// 9. Convert the result from a byte string into base58.
-	address := base58.Encode(digestCheckSum)
-	w.blockchainAddress = address
-	return w
+func newWallet(scheme common.SignatureScheme, privateKey crypto.Signer) *Wallet {
+	return &Wallet{
+		privateKey:        privateKey,
+		scheme:            scheme,
+		blockchainAddress: scheme.Address(privateKey.Public()),
+	}
+}
+
+// AddressFromPublicKey creates the blockchain address of a public key.
+// The version byte of the address tells the signature scheme of the key.
+func AddressFromPublicKey(publicKey crypto.PublicKey) string {
+	return common.AddressFromPublicKey(publicKey)
 }
 
-func (w *Wallet) PrivateKey() *ecdsa.PrivateKey {
+func (w *Wallet) PrivateKey() crypto.Signer {
 	return w.privateKey
 }
 
 func (w *Wallet) PrivateKeyStr() string {
-	return fmt.Sprintf("%x", w.privateKey.D.Bytes())
+	return hex.EncodeToString(w.scheme.PrivateKeyBytes(w.privateKey))
 }
 
-func (w *Wallet) PublicKey() *ecdsa.PublicKey {
-	return w.publicKey
+func (w *Wallet) PublicKey() crypto.PublicKey {
+	return w.privateKey.Public()
 }
 
 func (w *Wallet) PublicKeyStr() string {
-	return fmt.Sprintf("%064x%064x", w.publicKey.X, w.publicKey.Y)
+	return w.scheme.EncodePublicKey(w.PublicKey())
+}
+
+func (w *Wallet) Scheme() common.SignatureScheme {
+	return w.scheme
 }
 
 func (w *Wallet) BlockchainAddress() string {
@@ -89,9 +83,11 @@ func (w *Wallet) MarshalJSON() ([]byte, error) {
 		PrivateKey        string `json:"private_key"`
 		PublicKey         string `json:"public_key"`
 		BlockchainAddress string `json:"blockchain_address"`
+		SignatureScheme   string `json:"signature_scheme"`
 	}{
 		PrivateKey:        w.PrivateKeyStr(),
 		PublicKey:         w.PublicKeyStr(),
 		BlockchainAddress: w.blockchainAddress,
+		SignatureScheme:   w.scheme.ID(),
 	})
 }
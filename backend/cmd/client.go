@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+var errNoNodeAvailable = errors.New("no blockchain node is available")
+
+// nodeClient talks to the blockchain nodes of a profile.
+// A node that is unreachable or unhealthy is skipped and the next one is tried, see do.
+type nodeClient struct {
+	nodes  []string
+	client *http.Client
+}
+
+func newNodeClient(p *Profile) *nodeClient {
+	return &nodeClient{
+		nodes:  p.Nodes,
+		client: &http.Client{Timeout: p.Timeout.Std()},
+	}
+}
+
+func isNodeFailure(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
+
+// do sends in as JSON and decodes the response into out. It returns the node that answered.
+// A GET fails over to the next node. Any other request, e.g. a signed transaction, may already
+// have been processed by a node that failed to answer, so it goes to the next node only when
+// the connection failed before the request was written.
+func (nc *nodeClient) do(method, path string, in, out interface{}) (string, error) {
+	idempotent := method == http.MethodGet
+	var body []byte
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return "", err
+		}
+		body = b
+	}
+	var lastErr error
+	for _, node := range nc.nodes {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequest(method, node+path, reader)
+		if err != nil {
+			return "", err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		var sent atomic.Bool
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			WroteHeaders: func() { sent.Store(true) },
+		}))
+		resp, err := nc.client.Do(req)
+		if err != nil {
+			if !idempotent && sent.Load() {
+				return "", fmt.Errorf("%s failed after the request was sent, so it may have been processed: %w", node, err)
+			}
+			lastErr = err
+			continue
+		}
+		if isNodeFailure(resp.StatusCode) && idempotent {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("%s: status %d", node, resp.StatusCode)
+			continue
+		}
+		return node, decodeResponse(resp, out)
+	}
+	if lastErr != nil {
+		return "", fmt.Errorf("%w: %v", errNoNodeAvailable, lastErr)
+	}
+	return "", errNoNodeAvailable
+}
+
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		var r common.Response
+		if json.Unmarshal(b, &r) == nil && r.Message != "" {
+			return fmt.Errorf("%s: %s", http.StatusText(resp.StatusCode), r.Message)
+		}
+		return errors.New(http.StatusText(resp.StatusCode))
+	}
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
+
+func (nc *nodeClient) get(path string, out interface{}) (string, error) {
+	return nc.do(http.MethodGet, path, nil, out)
+}
+
+func (nc *nodeClient) post(path string, in, out interface{}) (string, error) {
+	return nc.do(http.MethodPost, path, in, out)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/config"
+)
+
+const (
+	DEFAULT_PROFILE = "default"
+	DEFAULT_TIMEOUT = 5 * time.Second
+
+	envConfig     = "BCCTL_CONFIG"
+	envProfile    = "BCCTL_PROFILE"
+	envNodes      = "BCCTL_NODES"
+	envNetwork    = "BCCTL_NETWORK"
+	envPassphrase = "BCCTL_PASSPHRASE"
+)
+
+// Profile is a set of blockchain nodes and the keystore used with them,
+// e.g. one profile per devnet.
+type Profile struct {
+	Network     string          `json:"network"`      // main, test or regtest. Addresses of the other networks are rejected
+	Nodes       []string        `json:"nodes"`        // blockchain node URLs, tried in order. The first node of the network if empty
+	KeystoreDir string          `json:"keystore_dir"` // directory of the encrypted key files. Under the directory of the network if empty
+	Timeout     config.Duration `json:"timeout"`
+}
+
+// Config is the CLI config file, ~/.bcctl/config.json by default.
+type Config struct {
+	CurrentProfile string              `json:"current_profile"`
+	Profiles       map[string]*Profile `json:"profiles"`
+
+	path string
+}
+
+func defaultConfigPath() string {
+	if p := os.Getenv(envConfig); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".bcctl", "config.json")
+	}
+	return filepath.Join(home, ".bcctl", "config.json")
+}
+
+// loadConfig reads the config file. A missing file is an empty config with the default profile.
+func loadConfig(path string) (*Config, error) {
+	c := &Config{
+		CurrentProfile: DEFAULT_PROFILE,
+		Profiles:       make(map[string]*Profile),
+		path:           path,
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*Profile)
+	}
+	return c, nil
+}
+
+func (c *Config) save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(b, '\n'), 0o600)
+}
+
+// defaultProfile is used for a profile that is not in the config file yet.
+// Its keystore is left to the network, see profile.
+func (c *Config) defaultProfile() *Profile {
+	return &Profile{
+		Network: common.NETWORK_MAIN,
+		Timeout: config.Duration(DEFAULT_TIMEOUT),
+	}
+}
+
+// profile returns the named profile with the defaults filled in. A network other than empty
+// overrides the one of the profile, together with its nodes, which are on the network of the profile.
+// The keystore is <config dir>/<network>/keystore/<profile> unless the profile names one.
+func (c *Config) profile(name, network string) *Profile {
+	merged := *c.defaultProfile()
+	if p, ok := c.Profiles[name]; ok {
+		merged = *p
+	}
+	if merged.Network == "" {
+		merged.Network = common.NETWORK_MAIN
+	}
+	if network != "" && network != merged.Network {
+		merged.Network = network
+		merged.Nodes = nil
+	}
+	if merged.KeystoreDir == "" {
+		merged.KeystoreDir = filepath.Join(filepath.Dir(c.path), merged.Network, "keystore", name)
+	}
+	if merged.Timeout <= 0 {
+		merged.Timeout = config.Duration(DEFAULT_TIMEOUT)
+	}
+	return &merged
+}
+
+// network resolves the network of the profile and fills in its default node.
+func (p *Profile) network() (*common.Network, error) {
+	n, err := common.NetworkByName(p.Network)
+	if err != nil {
+		return nil, err
+	}
+	p.Nodes = p.nodeURLs()
+	return n, nil
+}
+
+// nodeURLs are the nodes of the profile, or the first node of its network.
+func (p *Profile) nodeURLs() []string {
+	if len(p.Nodes) > 0 {
+		return p.Nodes
+	}
+	n, err := common.NetworkByName(p.Network)
+	if err != nil {
+		return nil
+	}
+	return []string{n.NodeURL()}
+}
+
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func splitNodes(s string) []string {
+	var nodes []string
+	for _, n := range strings.Split(s, ",") {
+		if n = strings.TrimRight(strings.TrimSpace(n), "/"); n != "" {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
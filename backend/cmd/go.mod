@@ -0,0 +1,29 @@
+module github.com/yagikota/blockchain_with_go/backend/cmd
+
+go 1.19
+
+require (
+	github.com/yagikota/blockchain_with_go/backend/common v0.0.0-20221113190538-e1e6c41ca063
+	github.com/yagikota/blockchain_with_go/backend/wallet v0.0.0-00010101000000-000000000000
+	golang.org/x/term v0.2.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/btcsuite/btcd/btcutil v1.1.2 // indirect
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
+	github.com/go-ozzo/ozzo-validation/v4 v4.3.0 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/prometheus/client_golang v1.14.0 // indirect
+	github.com/prometheus/client_model v0.3.0 // indirect
+	github.com/prometheus/common v0.37.0 // indirect
+	github.com/prometheus/procfs v0.8.0 // indirect
+	golang.org/x/crypto v0.2.0 // indirect
+	golang.org/x/sys v0.2.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+)
+
+// the wallet module is not published; it is always built from this repository.
+replace github.com/yagikota/blockchain_with_go/backend/wallet => ../wallet
@@ -1,11 +1,161 @@
+// Command bcctl is a command line client of the blockchain nodes and the wallet.
+// Keys are kept in an encrypted keystore on this machine and transactions are
+// signed locally, so private keys are never sent to a server. The passphrase of
+// a key is asked on the terminal, or taken from BCCTL_PASSPHRASE.
+//
+//	bcctl profile set devnet -nodes http://localhost:8001/v1,http://localhost:8002/v1
+//	bcctl profile set regtest -network regtest
+//	bcctl profile use devnet
+//	bcctl wallet create
+//	bcctl send -from <wallet> -to <address> -value 1.5
+//	bcctl -output json pool
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-
-	"github.com/yagikota/blockchain_with_go/backend/common"
+	"io"
+	"os"
+	"sort"
 )
 
+const usage = `Usage: bcctl [flags] <command> [args]
+
+Commands:
+  wallet create|import|export|list
+                              manage the keys in the local keystore
+  balance <wallet|address>    show the balance of an address
+  send                        sign a transaction locally and send it to a node
+  stake|unstake               lock or unlock stake on a pos chain
+  stakes [wallet|address]     show the stakes of a pos chain
+  pool                        show the transaction pool of a node
+  tx <id>                     show a transaction and whether it is confirmed
+  block [height|latest]       show a block
+  mine                        let a node mine a block
+  supply                      show the issued coins and the next halving
+  neighbors                   list the neighbors of a node
+  validators list|vote|discard
+                              show or change the votes of a poa node, signed with its key
+  profile list|show|use|set   manage the node profiles
+
+Flags:
+`
+
+type cli struct {
+	cfg         *Config
+	profileName string
+	profile     *Profile
+	out         *printer
+}
+
 func main() {
-	fmt.Println(common.FindNeighbors("localhost", 8000, 0, 3, 8000, 8003))
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("bcctl", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", defaultConfigPath(), "path to the config file (env "+envConfig+")")
+	profileName := fs.String("profile", os.Getenv(envProfile), "profile to use instead of the current one (env "+envProfile+")")
+	output := fs.String("output", OUTPUT_TABLE, "output format: table or json")
+	nodes := fs.String("nodes", os.Getenv(envNodes), "comma separated node URLs, overrides the profile (env "+envNodes+")")
+	networkName := fs.String("network", os.Getenv(envNetwork), "network: main, test or regtest, overrides the profile (env "+envNetwork+")")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *output != OUTPUT_TABLE && *output != OUTPUT_JSON {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	name := *profileName
+	if name == "" {
+		name = cfg.CurrentProfile
+	}
+	if name == "" {
+		name = DEFAULT_PROFILE
+	}
+	p := cfg.profile(name, *networkName)
+	if *nodes != "" {
+		p.Nodes = splitNodes(*nodes)
+	}
+	network, err := p.network()
+	if err != nil {
+		return err
+	}
+	network.Use()
+	c := &cli{
+		cfg:         cfg,
+		profileName: name,
+		profile:     p,
+		out:         &printer{format: *output, w: stdout},
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("command is required")
+	}
+	commands := c.commands()
+	command, ok := commands[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown command %q, one of %v", fs.Arg(0), commandNames(commands))
+	}
+	return command(fs.Args()[1:])
+}
+
+func (c *cli) commands() map[string]func(args []string) error {
+	return map[string]func(args []string) error{
+		"wallet":     c.wallet,
+		"balance":    c.balance,
+		"send":       c.send,
+		"stake":      c.stake,
+		"unstake":    c.unstake,
+		"stakes":     c.stakes,
+		"pool":       c.pool,
+		"tx":         c.tx,
+		"block":      c.block,
+		"mine":       c.mine,
+		"supply":     c.supply,
+		"neighbors":  c.neighbors,
+		"validators": c.validators,
+		"profile":    c.profiles,
+	}
+}
+
+func commandNames(commands map[string]func(args []string) error) []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// subcommand dispatches "<command> <sub> [args]".
+func subcommand(command string, args []string, subs map[string]func(args []string) error) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s: subcommand is required, one of %v", command, commandNames(subs))
+	}
+	sub, ok := subs[args[0]]
+	if !ok {
+		return fmt.Errorf("%s: unknown subcommand %q, one of %v", command, args[0], commandNames(subs))
+	}
+	return sub(args[1:])
+}
+
+func (c *cli) node() *nodeClient {
+	return newNodeClient(c.profile)
 }
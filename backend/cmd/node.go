@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+// responses of the blockchain node API.
+type transaction struct {
+	SenderBlockchainAddress    string                      `json:"sender_blockchain_address"`
+	RecipientBlockchainAddress string                      `json:"recipient_blockchain_address"`
+	Value                      float64                     `json:"value"`
+	Outputs                    []*common.TransactionOutput `json:"outputs,omitempty"`
+	Type                       string                      `json:"type,omitempty"`
+}
+
+// recipient is the recipient column of the tables. A batch shows how many it pays,
+// and stake, unstake and slash show their type.
+func (t *transaction) recipient() string {
+	if t.Type != "" {
+		return "(" + t.Type + ")"
+	}
+	if len(t.Outputs) > 0 {
+		return fmt.Sprintf("%d recipients", len(t.Outputs))
+	}
+	return t.RecipientBlockchainAddress
+}
+
+type poolResponse struct {
+	Transactions []*transaction `json:"transactions"`
+	Length       int            `json:"length"`
+}
+
+type blockResponse struct {
+	Height          int            `json:"height"`
+	Hash            string         `json:"hash"`
+	Timestamp       int64          `json:"timestamp"`
+	Nonce           int            `json:"nonce"`
+	PreviousHash    string         `json:"previous_hash"`
+	MerkleRoot      string         `json:"merkle_root"`
+	Difficulty      int            `json:"difficulty"`
+	Transactions    []*transaction `json:"transactions"`
+	Producer        string         `json:"producer"`
+	ProducerAddress string         `json:"producer_address"`
+}
+
+type transactionDetail struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	BlockHeight *int   `json:"block_height"`
+	transaction
+}
+
+type stake struct {
+	BlockchainAddress string  `json:"blockchain_address"`
+	Staked            float64 `json:"staked"`
+	Weight            float64 `json:"weight"`
+	Unbonding         []*struct {
+		Amount        float64 `json:"amount"`
+		ReleaseHeight int     `json:"release_height"`
+	} `json:"unbonding"`
+	Slashed bool `json:"slashed"`
+}
+
+type stakesResponse struct {
+	Stakes []*stake `json:"stakes"`
+	Length int      `json:"length"`
+}
+
+type supplyResponse struct {
+	CirculatingSupply float64 `json:"circulating_supply"`
+	TotalIssued       float64 `json:"total_issued"`
+	MaxSupply         float64 `json:"max_supply"`
+	Height            int     `json:"height"`
+	BlockReward       float64 `json:"block_reward"`
+	NextHalvingHeight int     `json:"next_halving_height"`
+}
+
+type neighborsResponse struct {
+	Neighbors []string `json:"neighbors"`
+	Length    int      `json:"length"`
+}
+
+func printTransactions(tw *tabwriter.Writer, transactions []*transaction) {
+	row(tw, "FROM", "TO", "VALUE")
+	for _, t := range transactions {
+		row(tw, t.SenderBlockchainAddress, t.recipient(), t.Value)
+	}
+}
+
+func (c *cli) pool(args []string) error {
+	var resp poolResponse
+	if _, err := c.node().get("/transactions", &resp); err != nil {
+		return err
+	}
+	return c.out.print(resp, func(tw *tabwriter.Writer) {
+		printTransactions(tw, resp.Transactions)
+	})
+}
+
+func (c *cli) tx(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: tx <id>")
+	}
+	var resp transactionDetail
+	if _, err := c.node().get("/transactions/"+url.PathEscape(args[0]), &resp); err != nil {
+		return err
+	}
+	return c.out.print(resp, func(tw *tabwriter.Writer) {
+		row(tw, "id", resp.ID)
+		row(tw, "status", resp.Status)
+		if resp.BlockHeight != nil {
+			row(tw, "block_height", *resp.BlockHeight)
+		}
+		row(tw, "from", resp.SenderBlockchainAddress)
+		if resp.Type != "" {
+			row(tw, "type", resp.Type)
+		} else if len(resp.Outputs) == 0 {
+			row(tw, "to", resp.RecipientBlockchainAddress)
+		}
+		for _, o := range resp.Outputs {
+			row(tw, "to", fmt.Sprintf("%s %v", o.RecipientBlockchainAddress, o.Value))
+		}
+		row(tw, "value", resp.Value)
+	})
+}
+
+func (c *cli) block(args []string) error {
+	fs := flag.NewFlagSet("block", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	height := "latest"
+	if fs.NArg() > 0 {
+		height = fs.Arg(0)
+	}
+	if _, err := strconv.Atoi(height); err != nil && height != "latest" {
+		return errors.New("usage: block [height|latest]")
+	}
+	var resp blockResponse
+	if _, err := c.node().get("/blocks/"+height, &resp); err != nil {
+		return err
+	}
+	return c.out.print(resp, func(tw *tabwriter.Writer) {
+		row(tw, "height", resp.Height)
+		row(tw, "hash", resp.Hash)
+		row(tw, "previous_hash", resp.PreviousHash)
+		row(tw, "merkle_root", resp.MerkleRoot)
+		row(tw, "timestamp", time.Unix(0, resp.Timestamp).Local().Format(time.RFC3339Nano))
+		row(tw, "nonce", resp.Nonce)
+		if resp.Difficulty > 0 {
+			row(tw, "difficulty", resp.Difficulty)
+		}
+		if resp.ProducerAddress != "" {
+			row(tw, "producer", resp.ProducerAddress)
+			row(tw, "producer_key", resp.Producer)
+		}
+		row(tw, "transactions", len(resp.Transactions))
+		if len(resp.Transactions) > 0 {
+			row(tw)
+			printTransactions(tw, resp.Transactions)
+		}
+	})
+}
+
+func (c *cli) mine(args []string) error {
+	var resp common.Response
+	if _, err := c.node().get("/mine", &resp); err != nil {
+		return err
+	}
+	return c.out.print(resp, func(tw *tabwriter.Writer) {
+		row(tw, resp.Message)
+	})
+}
+
+// stakes shows the stakes of a pos chain, or of one wallet or address.
+func (c *cli) stakes(args []string) error {
+	path := "/stakes"
+	if len(args) > 1 {
+		return errors.New("usage: stakes [wallet|address]")
+	}
+	if len(args) == 1 {
+		address, err := c.resolveAddress(args[0])
+		if err != nil {
+			return err
+		}
+		path += "?blockchain_address=" + url.QueryEscape(address)
+	}
+	var resp stakesResponse
+	if _, err := c.node().get(path, &resp); err != nil {
+		return err
+	}
+	return c.out.print(resp, func(tw *tabwriter.Writer) {
+		row(tw, "ADDRESS", "STAKED", "WEIGHT", "UNBONDING", "SLASHED")
+		for _, s := range resp.Stakes {
+			unbonding := 0.0
+			for _, u := range s.Unbonding {
+				unbonding += u.Amount
+			}
+			row(tw, s.BlockchainAddress, s.Staked, s.Weight, unbonding, s.Slashed)
+		}
+	})
+}
+
+func (c *cli) supply(args []string) error {
+	var resp supplyResponse
+	if _, err := c.node().get("/supply", &resp); err != nil {
+		return err
+	}
+	return c.out.print(resp, func(tw *tabwriter.Writer) {
+		row(tw, "circulating", resp.CirculatingSupply)
+		row(tw, "total_issued", resp.TotalIssued)
+		if resp.MaxSupply > 0 {
+			row(tw, "max_supply", resp.MaxSupply)
+		}
+		row(tw, "height", resp.Height)
+		row(tw, "block_reward", resp.BlockReward)
+		if resp.NextHalvingHeight > 0 {
+			row(tw, "next_halving", resp.NextHalvingHeight)
+		}
+	})
+}
+
+func (c *cli) neighbors(args []string) error {
+	var resp neighborsResponse
+	node, err := c.node().get("/neighbors", &resp)
+	if err != nil {
+		return err
+	}
+	return c.out.print(resp, func(tw *tabwriter.Writer) {
+		row(tw, "NEIGHBORS OF "+node)
+		for _, n := range resp.Neighbors {
+			row(tw, n)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/config"
+)
+
+type profileInfo struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+	*Profile
+}
+
+func (c *cli) profiles(args []string) error {
+	return subcommand("profile", args, map[string]func(args []string) error{
+		"list": c.listProfiles,
+		"show": c.showProfile,
+		"use":  c.useProfile,
+		"set":  c.setProfile,
+	})
+}
+
+func (c *cli) printProfiles(profiles []profileInfo) error {
+	return c.out.print(profiles, func(tw *tabwriter.Writer) {
+		row(tw, "", "NAME", "NETWORK", "NODES", "KEYSTORE", "TIMEOUT")
+		for _, p := range profiles {
+			current := ""
+			if p.Current {
+				current = "*"
+			}
+			row(tw, current, p.Name, p.Network, strings.Join(p.nodeURLs(), ","), p.KeystoreDir, p.Timeout.Std())
+		}
+	})
+}
+
+func (c *cli) listProfiles(args []string) error {
+	names := c.cfg.profileNames()
+	profiles := make([]profileInfo, 0, len(names))
+	for _, name := range names {
+		profiles = append(profiles, profileInfo{Name: name, Current: name == c.cfg.CurrentProfile, Profile: c.cfg.profile(name, "")})
+	}
+	return c.printProfiles(profiles)
+}
+
+// showProfile shows the profile in effect, including the flag and environment overrides.
+func (c *cli) showProfile(args []string) error {
+	return c.printProfiles([]profileInfo{{Name: c.profileName, Current: true, Profile: c.profile}})
+}
+
+func (c *cli) useProfile(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: profile use <name>")
+	}
+	if _, ok := c.cfg.Profiles[args[0]]; !ok && args[0] != DEFAULT_PROFILE {
+		return fmt.Errorf("profile %q does not exist, create it with profile set", args[0])
+	}
+	c.cfg.CurrentProfile = args[0]
+	return c.cfg.save()
+}
+
+// setProfile creates a profile or updates the given fields of it.
+func (c *cli) setProfile(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New("usage: profile set <name> [-network main|test|regtest] [-nodes url,...] [-keystore dir] [-timeout 5s]")
+	}
+	name := args[0]
+	fs := flag.NewFlagSet("profile set", flag.ContinueOnError)
+	network := fs.String("network", "", "network of the nodes: main, test or regtest")
+	nodes := fs.String("nodes", "", "comma separated node URLs (default: the first node of the network)")
+	keystoreDir := fs.String("keystore", "", "directory of the encrypted key files")
+	timeout := fs.Duration("timeout", 0, "timeout of a request to a node")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	p, ok := c.cfg.Profiles[name]
+	if !ok {
+		p = c.cfg.defaultProfile()
+		c.cfg.Profiles[name] = p
+	}
+	if *network != "" {
+		if _, err := common.NetworkByName(*network); err != nil {
+			return err
+		}
+		p.Network = *network
+	}
+	if *nodes != "" {
+		p.Nodes = splitNodes(*nodes)
+	}
+	if *keystoreDir != "" {
+		p.KeystoreDir = *keystoreDir
+	}
+	if *timeout < 0 {
+		return errors.New("-timeout must not be negative")
+	}
+	if *timeout > 0 {
+		p.Timeout = config.Duration(*timeout)
+	}
+	if len(c.cfg.Profiles) == 1 {
+		c.cfg.CurrentProfile = name
+	}
+	if err := c.cfg.save(); err != nil {
+		return err
+	}
+	return c.printProfiles([]profileInfo{{Name: name, Current: name == c.cfg.CurrentProfile, Profile: c.cfg.profile(name, "")}})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+type validatorVote struct {
+	PublicKey string `json:"public_key"`
+	Authorize bool   `json:"authorize"`
+}
+
+type validatorsResponse struct {
+	Validators []string         `json:"validators"`
+	Length     int              `json:"length"`
+	Self       string           `json:"self"`
+	Validator  bool             `json:"validator"`
+	Proposals  []*validatorVote `json:"proposals"`
+}
+
+type validatorVoteRequest struct {
+	validatorVote
+	Timestamp int64  `json:"timestamp"`
+	Signature string `json:"signature"`
+}
+
+// validators manages the votes of a poa node. A node only accepts votes signed with its own key,
+// so the key of the node has to be imported into the keystore first, and the request goes to that node only.
+func (c *cli) validators(args []string) error {
+	return subcommand("validators", args, map[string]func(args []string) error{
+		"list":    c.listValidators,
+		"vote":    c.voteValidator,
+		"discard": c.discardValidatorVote,
+	})
+}
+
+func (c *cli) listValidators(args []string) error {
+	var resp validatorsResponse
+	if _, err := c.node().get("/validators", &resp); err != nil {
+		return err
+	}
+	return c.printValidators(resp)
+}
+
+func (c *cli) printValidators(resp validatorsResponse) error {
+	return c.out.print(resp, func(tw *tabwriter.Writer) {
+		row(tw, "VALIDATOR", "SELF")
+		for _, v := range resp.Validators {
+			row(tw, v, v == resp.Self)
+		}
+		if len(resp.Proposals) == 0 {
+			return
+		}
+		row(tw)
+		row(tw, "VOTE", "AUTHORIZE")
+		for _, p := range resp.Proposals {
+			row(tw, p.PublicKey, p.Authorize)
+		}
+	})
+}
+
+// validatorFlags are the flags of vote and discard: the node to send to and the keystore key of the node.
+func validatorFlags(fs *flag.FlagSet) (node, from, publicKey *string) {
+	node = fs.String("node", "", "URL of the node whose votes to change. The first node of the profile if empty")
+	from = fs.String("from", "", "keystore ID or address of the key of the node")
+	publicKey = fs.String("public-key", "", "public key of the validator to vote for")
+	return node, from, publicKey
+}
+
+func (c *cli) voteValidator(args []string) error {
+	fs := flag.NewFlagSet("validators vote", flag.ContinueOnError)
+	node, from, publicKey := validatorFlags(fs)
+	remove := fs.Bool("remove", false, "vote for removing the validator instead of adding it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	timestamp := time.Now().UnixNano()
+	r := validatorVoteRequest{validatorVote: validatorVote{PublicKey: *publicKey, Authorize: !*remove}, Timestamp: timestamp}
+	signature, err := c.signValidatorRequest(*from, *publicKey, common.ValidatorVoteMessage(r.PublicKey, r.Authorize, timestamp))
+	if err != nil {
+		return err
+	}
+	r.Signature = signature
+	var resp validatorsResponse
+	if _, err := c.validatorNode(*node).post("/validators/votes", r, &resp); err != nil {
+		return err
+	}
+	return c.printValidators(resp)
+}
+
+func (c *cli) discardValidatorVote(args []string) error {
+	fs := flag.NewFlagSet("validators discard", flag.ContinueOnError)
+	node, from, publicKey := validatorFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	timestamp := time.Now().UnixNano()
+	signature, err := c.signValidatorRequest(*from, *publicKey, common.ValidatorDiscardMessage(*publicKey, timestamp))
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/validators/votes/%s?timestamp=%d&signature=%s", url.PathEscape(*publicKey), timestamp, signature)
+	var resp validatorsResponse
+	if _, err := c.validatorNode(*node).do(http.MethodDelete, path, nil, &resp); err != nil {
+		return err
+	}
+	return c.printValidators(resp)
+}
+
+func (c *cli) signValidatorRequest(from, publicKey, message string) (string, error) {
+	if from == "" || publicKey == "" {
+		return "", errors.New("-from and -public-key are required")
+	}
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return "", err
+	}
+	ks, err := c.keystore()
+	if err != nil {
+		return "", err
+	}
+	w, err := ks.Open(from, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(common.SignMessage(w.Scheme(), w.PrivateKey(), message)), nil
+}
+
+// validatorNode is a client of the one node whose key signs the request, without failing over to the others.
+func (c *cli) validatorNode(node string) *nodeClient {
+	nc := c.node()
+	if node == "" && len(nc.nodes) > 0 {
+		node = nc.nodes[0]
+	}
+	nc.nodes = []string{node}
+	return nc
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/keystore"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
+	"golang.org/x/term"
+)
+
+// keyInfo is the public part of a key in the keystore.
+type keyInfo struct {
+	ID                string    `json:"id"`
+	BlockchainAddress string    `json:"blockchain_address"`
+	PublicKey         string    `json:"public_key"`
+	Curve             string    `json:"curve,omitempty"`
+	SignatureScheme   string    `json:"signature_scheme"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func newKeyInfo(kf *keystore.KeyFile) keyInfo {
+	k := keyInfo{
+		ID:                kf.ID,
+		BlockchainAddress: kf.BlockchainAddress,
+		PublicKey:         kf.PublicKey,
+		CreatedAt:         kf.CreatedAt,
+	}
+	if scheme, err := common.SchemeOfAddress(kf.BlockchainAddress); err == nil {
+		k.SignatureScheme = scheme.ID()
+		k.Curve = common.CurveOfScheme(scheme)
+	}
+	return k
+}
+
+type balanceResult struct {
+	BlockchainAddress string  `json:"blockchain_address"`
+	Amount            float64 `json:"amount"`
+	Spendable         float64 `json:"spendable"`
+	Immature          float64 `json:"immature"`
+	Pending           float64 `json:"pending"`
+	Node              string  `json:"node"`
+}
+
+type sendResult struct {
+	model.BlockchainTransactionRequest
+	ID   string `json:"id"`
+	Node string `json:"node"`
+}
+
+func (c *cli) keystore() (*keystore.Keystore, error) {
+	return keystore.New(c.profile.KeystoreDir)
+}
+
+// readPassphrase takes the passphrase of a key from BCCTL_PASSPHRASE, or asks for it on the terminal.
+// There is no flag for it, so that it never shows up in the shell history or the process list.
+// confirm asks twice, for the passphrase of a new key.
+func readPassphrase(confirm bool) (string, error) {
+	if env := os.Getenv(envPassphrase); env != "" {
+		return env, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("%w: set %s or run in a terminal", keystore.ErrEmptyPassphrase, envPassphrase)
+	}
+	passphrase, err := promptPassphrase(fd, "Passphrase: ")
+	if err != nil || !confirm {
+		return passphrase, err
+	}
+	again, err := promptPassphrase(fd, "Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", errors.New("the passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+func promptPassphrase(fd int, prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(b) == 0 {
+		return "", keystore.ErrEmptyPassphrase
+	}
+	return string(b), nil
+}
+
+func (c *cli) wallet(args []string) error {
+	return subcommand("wallet", args, map[string]func(args []string) error{
+		"create": c.createWallet,
+		"import": c.importWallet,
+		"export": c.exportWallet,
+		"list":   c.listWallets,
+	})
+}
+
+func (c *cli) createWallet(args []string) error {
+	fs := flag.NewFlagSet("wallet create", flag.ContinueOnError)
+	curve := fs.String("curve", "", "curve of an ECDSA key: P-256 or secp256k1")
+	schemeID := fs.String("scheme", "", "signature scheme: ecdsa-p256, ecdsa-secp256k1 or ed25519 (default: ecdsa-p256)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	scheme, err := common.SchemeByName(*schemeID, *curve)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(true)
+	if err != nil {
+		return err
+	}
+	ks, err := c.keystore()
+	if err != nil {
+		return err
+	}
+	kf, err := ks.Create(passphrase, scheme)
+	if err != nil {
+		return err
+	}
+	return c.printKeys([]keyInfo{newKeyInfo(kf)})
+}
+
+// importWallet stores an exported private key. The format is detected unless -format is given.
+func (c *cli) importWallet(args []string) error {
+	fs := flag.NewFlagSet("wallet import", flag.ContinueOnError)
+	privateKey := fs.String("private-key", "", "private key in hex, WIF or PEM")
+	file := fs.String("file", "", "read the private key from a file, e.g. a PEM file")
+	format := fs.String("format", "", "format of the private key: hex, wif or pem (default: detect)")
+	curve := fs.String("curve", "", "curve of the key: P-256 or secp256k1 (default: detect)")
+	schemeID := fs.String("scheme", "", "signature scheme of the key, required for hex of an ed25519 seed (default: detect)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	key := *privateKey
+	if *file != "" {
+		b, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		key = string(b)
+	}
+	if key == "" {
+		return errors.New("-private-key or -file is required")
+	}
+	scheme, err := common.SchemeByName(*schemeID, *curve)
+	if err != nil {
+		return err
+	}
+	w, err := model.ParsePrivateKey(key, *format, scheme)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(true)
+	if err != nil {
+		return err
+	}
+	ks, err := c.keystore()
+	if err != nil {
+		return err
+	}
+	kf, err := ks.Import(w, passphrase)
+	if err != nil {
+		return err
+	}
+	return c.printKeys([]keyInfo{newKeyInfo(kf)})
+}
+
+func (c *cli) exportWallet(args []string) error {
+	fs := flag.NewFlagSet("wallet export", flag.ContinueOnError)
+	format := fs.String("format", model.KEY_FORMAT_WIF, "format of the private key: hex, wif or pem")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: wallet export [-format wif|hex|pem] <wallet>")
+	}
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return err
+	}
+	ks, err := c.keystore()
+	if err != nil {
+		return err
+	}
+	w, err := ks.Open(fs.Arg(0), passphrase)
+	if err != nil {
+		return err
+	}
+	privateKey, err := w.ExportPrivateKey(*format)
+	if err != nil {
+		return err
+	}
+	result := model.KeyExportResponse{
+		Format:            *format,
+		PrivateKey:        privateKey,
+		PublicKey:         w.PublicKeyStr(),
+		BlockchainAddress: w.BlockchainAddress(),
+	}
+	return c.out.print(result, func(tw *tabwriter.Writer) {
+		row(tw, "address", result.BlockchainAddress)
+		row(tw, "format", result.Format)
+		if *format == model.KEY_FORMAT_PEM {
+			fmt.Fprint(tw, result.PrivateKey)
+			return
+		}
+		row(tw, "private_key", result.PrivateKey)
+	})
+}
+
+func (c *cli) listWallets(args []string) error {
+	ks, err := c.keystore()
+	if err != nil {
+		return err
+	}
+	kfs, err := ks.List()
+	if err != nil {
+		return err
+	}
+	keys := make([]keyInfo, 0, len(kfs))
+	for _, kf := range kfs {
+		keys = append(keys, newKeyInfo(kf))
+	}
+	return c.printKeys(keys)
+}
+
+func (c *cli) printKeys(keys []keyInfo) error {
+	return c.out.print(keys, func(tw *tabwriter.Writer) {
+		row(tw, "ID", "ADDRESS", "SCHEME", "CREATED")
+		for _, k := range keys {
+			row(tw, k.ID, k.BlockchainAddress, k.SignatureScheme, k.CreatedAt.Local().Format(time.RFC3339))
+		}
+	})
+}
+
+// resolveAddress accepts a keystore ID, or an address as is.
+func (c *cli) resolveAddress(idOrAddress string) (string, error) {
+	ks, err := c.keystore()
+	if err != nil {
+		return "", err
+	}
+	kf, err := ks.Find(idOrAddress)
+	if errors.Is(err, keystore.ErrNotFound) {
+		// an address of another network is not a wallet of this profile either
+		return idOrAddress, model.ValidateAddress(idOrAddress)
+	}
+	if err != nil {
+		return "", err
+	}
+	return kf.BlockchainAddress, nil
+}
+
+func (c *cli) balance(args []string) error {
+	fs := flag.NewFlagSet("balance", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: balance <wallet|address>")
+	}
+	address, err := c.resolveAddress(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var resp common.AmountResponse
+	node, err := c.node().get("/amount?blockchain_address="+url.QueryEscape(address), &resp)
+	if err != nil {
+		return err
+	}
+	result := balanceResult{
+		BlockchainAddress: address,
+		Amount:            resp.Amount,
+		Spendable:         resp.Spendable,
+		Immature:          resp.Immature,
+		Pending:           resp.Pending,
+		Node:              node,
+	}
+	return c.out.print(result, func(tw *tabwriter.Writer) {
+		row(tw, "ADDRESS", "AMOUNT", "SPENDABLE", "IMMATURE", "PENDING")
+		row(tw, result.BlockchainAddress, result.Amount, result.Spendable, result.Immature, result.Pending)
+	})
+}
+
+// send signs the transaction with a key of the keystore and sends only the signature to a node.
+func (c *cli) send(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	from := fs.String("from", "", "keystore ID or address of the sender")
+	to := fs.String("to", "", "address of the recipient")
+	value := fs.Float64("value", 0, "amount to send")
+	payouts := fs.String("payouts", "", "CSV or JSON file of recipients to pay in one batch transaction")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return errors.New("-from is required")
+	}
+	var outputs []*common.TransactionOutput
+	if *payouts != "" {
+		if *to != "" {
+			return errors.New("-to and -payouts can't be used together")
+		}
+		var err error
+		if outputs, err = readPayouts(*payouts); err != nil {
+			return err
+		}
+	} else {
+		if *to == "" {
+			return errors.New("-to or -payouts is required")
+		}
+		if err := model.ValidateAddress(*to); err != nil {
+			return fmt.Errorf("-to: %w", err)
+		}
+		if *value <= 0 {
+			return errors.New("-value must be positive")
+		}
+	}
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return err
+	}
+	ks, err := c.keystore()
+	if err != nil {
+		return err
+	}
+	w, err := ks.Open(*from, passphrase)
+	if err != nil {
+		return err
+	}
+	nonce, err := c.nextNonce(w.BlockchainAddress())
+	if err != nil {
+		return err
+	}
+	if outputs != nil {
+		return c.sendBatch(w, outputs, nonce)
+	}
+
+	t := model.NewTransaction(w.PrivateKey(), w.PublicKey(), w.BlockchainAddress(), *to, *value, nonce)
+	bt := model.BlockchainTransactionRequest{
+		SenderBlockchainAddress:    w.BlockchainAddress(),
+		RecipientBlockchainAddress: *to,
+		SenderPublicKey:            w.PublicKeyStr(),
+		Value:                      *value,
+		Signature:                  t.GenerateSignature(),
+		SignatureScheme:            w.Scheme().ID(),
+		Nonce:                      nonce,
+	}
+	if err := bt.Validate(); err != nil {
+		return err
+	}
+	var result sendResult
+	node, err := c.node().post("/transactions", bt, &result)
+	if err != nil {
+		return fmt.Errorf("transaction was rejected: %w", err)
+	}
+	result.BlockchainTransactionRequest, result.Node = bt, node
+	return c.out.print(result, func(tw *tabwriter.Writer) {
+		row(tw, "ID", "FROM", "TO", "VALUE", "NODE")
+		row(tw, result.ID, bt.SenderBlockchainAddress, bt.RecipientBlockchainAddress, bt.Value, node)
+	})
+}
+
+func (c *cli) sendBatch(w *model.Wallet, outputs []*common.TransactionOutput, nonce uint64) error {
+	t := model.NewBatchTransaction(w.PrivateKey(), w.PublicKey(), w.BlockchainAddress(), outputs, nonce)
+	bt := model.BlockchainTransactionRequest{
+		SenderBlockchainAddress: w.BlockchainAddress(),
+		SenderPublicKey:         w.PublicKeyStr(),
+		Value:                   t.Value,
+		Outputs:                 outputs,
+		Signature:               t.GenerateSignature(),
+		SignatureScheme:         w.Scheme().ID(),
+		Nonce:                   nonce,
+	}
+	if err := bt.Validate(); err != nil {
+		return err
+	}
+	var result sendResult
+	node, err := c.node().post("/transactions", bt, &result)
+	if err != nil {
+		return fmt.Errorf("transaction was rejected: %w", err)
+	}
+	result.BlockchainTransactionRequest, result.Node = bt, node
+	return c.out.print(result, func(tw *tabwriter.Writer) {
+		row(tw, "ID", "FROM", "TO", "VALUE", "NODE")
+		for _, o := range outputs {
+			row(tw, result.ID, bt.SenderBlockchainAddress, o.RecipientBlockchainAddress, o.Value, node)
+		}
+		row(tw, "", "", "total", bt.Value, "")
+	})
+}
+
+// nextNonce asks the node for the nonce to sign the next transaction of address with.
+func (c *cli) nextNonce(address string) (uint64, error) {
+	var resp model.NonceResponse
+	if _, err := c.node().get("/nonce?blockchain_address="+url.QueryEscape(address), &resp); err != nil {
+		return 0, err
+	}
+	return resp.Nonce, nil
+}
+
+// stake locks value of the wallet as stake on a pos chain.
+func (c *cli) stake(args []string) error {
+	return c.sendStake(common.TRANSACTION_TYPE_STAKE, args)
+}
+
+// unstake unlocks value of the stake. It can be spent after the lock-up period.
+func (c *cli) unstake(args []string) error {
+	return c.sendStake(common.TRANSACTION_TYPE_UNSTAKE, args)
+}
+
+func (c *cli) sendStake(typ string, args []string) error {
+	fs := flag.NewFlagSet(typ, flag.ContinueOnError)
+	from := fs.String("from", "", "keystore ID or address of the staker")
+	value := fs.Float64("value", 0, "amount to "+typ)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return errors.New("-from is required")
+	}
+	if *value <= 0 {
+		return errors.New("-value must be positive")
+	}
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return err
+	}
+	ks, err := c.keystore()
+	if err != nil {
+		return err
+	}
+	w, err := ks.Open(*from, passphrase)
+	if err != nil {
+		return err
+	}
+	nonce, err := c.nextNonce(w.BlockchainAddress())
+	if err != nil {
+		return err
+	}
+	t := model.NewStakeTransaction(w.PrivateKey(), w.PublicKey(), w.BlockchainAddress(), typ, *value, nonce)
+	bt := model.BlockchainTransactionRequest{
+		SenderBlockchainAddress: w.BlockchainAddress(),
+		SenderPublicKey:         w.PublicKeyStr(),
+		Value:                   *value,
+		Signature:               t.GenerateSignature(),
+		SignatureScheme:         w.Scheme().ID(),
+		Type:                    typ,
+		Nonce:                   nonce,
+	}
+	if err := bt.Validate(); err != nil {
+		return err
+	}
+	var result sendResult
+	node, err := c.node().post("/transactions", bt, &result)
+	if err != nil {
+		return fmt.Errorf("transaction was rejected: %w", err)
+	}
+	result.BlockchainTransactionRequest, result.Node = bt, node
+	return c.out.print(result, func(tw *tabwriter.Writer) {
+		row(tw, "ID", "FROM", "TYPE", "VALUE", "NODE")
+		row(tw, result.ID, bt.SenderBlockchainAddress, typ, bt.Value, node)
+	})
+}
+
+// readPayouts reads the recipients of a batch. A .json file is an array of
+// {"recipient_blockchain_address", "value"}; anything else is CSV of address,value.
+// Contacts of the wallet server's address book can't be used here.
+func readPayouts(path string) ([]*common.TransactionOutput, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var payouts []*model.Payout
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.NewDecoder(f).Decode(&payouts)
+	} else {
+		payouts, err = model.ParsePayoutsCSV(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	outputs := make([]*common.TransactionOutput, 0, len(payouts))
+	for i, p := range payouts {
+		if p.RecipientContact != "" {
+			return nil, fmt.Errorf("%s: payout %d: %q is not an address", path, i+1, p.RecipientContact)
+		}
+		if err := model.ValidateAddress(p.RecipientBlockchainAddress); err != nil {
+			return nil, fmt.Errorf("%s: payout %d: %w", path, i+1, err)
+		}
+		outputs = append(outputs, &common.TransactionOutput{
+			RecipientBlockchainAddress: p.RecipientBlockchainAddress,
+			Value:                      p.Value,
+		})
+	}
+	if err := common.ValidateOutputs(outputs); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
@@ -0,0 +1,37 @@
+package common
+
+// ChainResponse is GET /chain of the blockchain node, as read by its clients.
+type ChainResponse struct {
+	Chain []*Block `json:"chains"`
+}
+
+// Block is a block of GET /chain. The node only serves it, so the fields of the
+// consensus (vote, signature, ...) are left out.
+type Block struct {
+	Timestamp    int64               `json:"timestamp"`
+	Nonce        int                 `json:"nonce"`
+	PreviousHash string              `json:"previous_hash"`
+	MerkleRoot   string              `json:"merkle_root"`
+	Difficulty   int                 `json:"difficulty"`
+	Transactions []*BlockTransaction `json:"transactions"`
+	Producer     string              `json:"producer,omitempty"`
+	Height       int                 `json:"height,omitempty"`
+}
+
+type BlockTransaction struct {
+	SenderBlockchainAddress    string               `json:"sender_blockchain_address"`
+	RecipientBlockchainAddress string               `json:"recipient_blockchain_address"`
+	Value                      float64              `json:"value"`
+	Outputs                    []*TransactionOutput `json:"outputs,omitempty"`
+	Type                       string               `json:"type,omitempty"`
+	Nonce                      uint64               `json:"nonce"`
+}
+
+// AmountResponse is GET /amount of the blockchain node: the balance of an address.
+// Amount is Spendable + Immature + Pending.
+type AmountResponse struct {
+	Amount    float64 `json:"amount"`
+	Spendable float64 `json:"spendable"`
+	Immature  float64 `json:"immature"` // rewards younger than the coinbase maturity
+	Pending   float64 `json:"pending"`  // spent by transactions in the pool
+}
@@ -0,0 +1,142 @@
+package common
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// The curve is a property of the key, and the version byte of an address tells
+// which curve its key is on, see SchemeOfAddress.
+const (
+	CURVE_P256      = "P-256"
+	CURVE_SECP256K1 = "secp256k1"
+
+	ADDRESS_VERSION_P256      byte = 0x00 // addresses start with "1"
+	ADDRESS_VERSION_SECP256K1 byte = 0x3f // addresses start with "S"
+)
+
+// Curves are the names accepted by SchemeByName, for validation.In.
+var Curves = []interface{}{CURVE_P256, CURVE_SECP256K1}
+
+var (
+	ErrUnknownCurve   = errors.New("unknown curve, one of P-256 or secp256k1")
+	ErrInvalidAddress = errors.New("invalid blockchain address: bad length, version or checksum")
+)
+
+// CurveOfScheme is the name of the curve of an ECDSA scheme, and empty for the other schemes.
+func CurveOfScheme(scheme SignatureScheme) string {
+	if s, ok := scheme.(*ecdsaScheme); ok {
+		return curveName(s.curve)
+	}
+	return ""
+}
+
+func curveName(curve elliptic.Curve) string {
+	if curve == secp256k1.S256() {
+		return CURVE_SECP256K1
+	}
+	return CURVE_P256
+}
+
+// AddressFromPublicKey creates the blockchain address of a public key of any scheme.
+// It is empty for a key of no scheme.
+func AddressFromPublicKey(publicKey crypto.PublicKey) string {
+	scheme, err := SchemeOfPublicKey(publicKey)
+	if err != nil {
+		return ""
+	}
+	return scheme.Address(publicKey)
+}
+
+func ValidateAddress(address string) error {
+	_, err := SchemeOfAddress(address)
+	return err
+}
+
+func addressChecksum(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:4]
+}
+
+// CompressPublicKey encodes a public key in 33 bytes: 0x02 or 0x03 for the parity of Y, then X.
+func CompressPublicKey(publicKey *ecdsa.PublicKey) []byte {
+	b := make([]byte, 33)
+	b[0] = 0x02 + byte(publicKey.Y.Bit(0))
+	publicKey.X.FillBytes(b[1:])
+	return b
+}
+
+func CompressedPublicKeyString(publicKey *ecdsa.PublicKey) string {
+	return hex.EncodeToString(CompressPublicKey(publicKey))
+}
+
+// ParseCurvePublicKey parses a public key on curve from hex of any of
+// X || Y (64 bytes), the compressed key (33 bytes) or 0x04 || X || Y (65 bytes).
+func ParseCurvePublicKey(curve elliptic.Curve, s string) (*ecdsa.PublicKey, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	if len(b) == 64 {
+		b = append([]byte{0x04}, b...)
+	}
+	if curveName(curve) == CURVE_SECP256K1 {
+		// ParsePubKey checks the prefix, the length and that the point is on the curve.
+		k, err := secp256k1.ParsePubKey(b)
+		if err != nil {
+			return nil, ErrInvalidPublicKey
+		}
+		return k.ToECDSA(), nil
+	}
+	var x, y *big.Int
+	switch {
+	case len(b) == 33 && (b[0] == 0x02 || b[0] == 0x03):
+		x, y = elliptic.UnmarshalCompressed(curve, b)
+	case len(b) == 65 && b[0] == 0x04:
+		x, y = new(big.Int).SetBytes(b[1:33]), new(big.Int).SetBytes(b[33:])
+		if !curve.IsOnCurve(x, y) {
+			x = nil
+		}
+	}
+	if x == nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// ParsePublicKeyForAddress parses a public key of the scheme of the address.
+// It doesn't check that the key is the key of the address; compare AddressFromPublicKey for that.
+func ParsePublicKeyForAddress(s, address string) (crypto.PublicKey, error) {
+	scheme, err := SchemeOfAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return scheme.ParsePublicKey(s)
+}
+
+// CurvePrivateKeyFromBytes restores a private key on curve and derives its public key.
+// d must be in [1, n-1].
+func CurvePrivateKeyFromBytes(curve elliptic.Curve, d []byte) (*ecdsa.PrivateKey, error) {
+	k := new(big.Int).SetBytes(d)
+	if len(d) > 32 || k.Sign() == 0 || k.Cmp(curve.Params().N) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	privateKey := &ecdsa.PrivateKey{D: k}
+	privateKey.PublicKey.Curve = curve
+	privateKey.PublicKey.X, privateKey.PublicKey.Y = curve.ScalarBaseMult(intToOctets(k, 32))
+	return privateKey, nil
+}
+
+// PublicKeyString is the 128 hex characters of X || Y, the encoding every client understands.
+func PublicKeyString(publicKey *ecdsa.PublicKey) string {
+	return fmt.Sprintf("%064x%064x", publicKey.X, publicKey.Y)
+}
@@ -6,26 +6,105 @@ package common
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"encoding/asn1"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
 )
 
+var (
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+	ErrInvalidPublicKey  = errors.New("invalid public key: bad encoding or not a point on the curve")
+	ErrInvalidSignature  = errors.New("invalid signature encoding")
+)
+
+// Signature is an ECDSA signature. Its canonical form has a low S (S <= N/2):
+// (R, N-S) is valid for the same message too, so only one of the two is accepted.
 type Signature struct {
 	R *big.Int
 	S *big.Int
 }
 
+// String is the fixed 128 hex characters of R || S.
 func (s *Signature) String() string {
 	return fmt.Sprintf("%064x%064x", s.R, s.S)
 }
 
-func String2BigIntTuple(s string) (big.Int, big.Int) {
-	bx, _ := hex.DecodeString(s[:64])
-	by, _ := hex.DecodeString(s[64:])
+// Bytes are the 64 bytes of R || S.
+func (s *Signature) Bytes() []byte {
+	b := make([]byte, 64)
+	s.R.FillBytes(b[:32])
+	s.S.FillBytes(b[32:])
+	return b
+}
+
+// SignatureFromBytes splits the 64 bytes of Bytes.
+func SignatureFromBytes(b []byte) *Signature {
+	return &Signature{R: new(big.Int).SetBytes(b[:32]), S: new(big.Int).SetBytes(b[32:])}
+}
+
+// DER is the ASN.1 DER encoding used by OpenSSL and most other libraries.
+func (s *Signature) DER() []byte {
+	b, _ := asn1.Marshal(struct{ R, S *big.Int }{s.R, s.S})
+	return b
+}
+
+func (s *Signature) DERString() string {
+	return hex.EncodeToString(s.DER())
+}
+
+// IsLowS reports whether S is in the lower half of the curve order.
+func (s *Signature) IsLowS(curve elliptic.Curve) bool {
+	halfOrder := new(big.Int).Rsh(curve.Params().N, 1)
+	return s.S.Cmp(halfOrder) <= 0
+}
+
+// ParseSignature parses either String (128 hex characters) or DERString.
+// DER must be strictly encoded, with nothing after the sequence.
+// Whether R and S are in range of the curve is checked by VerifyDigest.
+func ParseSignature(s string) (*Signature, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	var sig Signature
+	switch {
+	case len(b) == 64:
+		sig.R = new(big.Int).SetBytes(b[:32])
+		sig.S = new(big.Int).SetBytes(b[32:])
+	case len(b) > 0 && b[0] == 0x30:
+		var der struct{ R, S *big.Int }
+		rest, err := asn1.Unmarshal(b, &der)
+		if err != nil || len(rest) > 0 {
+			return nil, ErrInvalidSignature
+		}
+		sig.R, sig.S = der.R, der.S
+	default:
+		return nil, ErrInvalidSignature
+	}
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return nil, ErrInvalidSignature
+	}
+	return &sig, nil
+}
 
+// ParsePublicKey parses a P-256 public key in any encoding of ParseCurvePublicKey.
+// Use ParsePublicKeyForAddress when the address of the key is known.
+func ParsePublicKey(s string) (*ecdsa.PublicKey, error) {
+	return ParseCurvePublicKey(elliptic.P256(), s)
+}
+
+// Deprecated: it decodes anything, and returns zeros when s is not 128 characters.
+// Use ParseSignature or ParsePublicKey, which return an error instead.
+func String2BigIntTuple(s string) (big.Int, big.Int) {
 	var bix big.Int
 	var biy big.Int
+	if len(s) != 128 {
+		return bix, biy
+	}
+	bx, _ := hex.DecodeString(s[:64])
+	by, _ := hex.DecodeString(s[64:])
 
 	_ = bix.SetBytes(bx)
 	_ = biy.SetBytes(by)
@@ -33,11 +112,13 @@ func String2BigIntTuple(s string) (big.Int, big.Int) {
 	return bix, biy
 }
 
+// Deprecated: use ParseSignature.
 func SignatureFromString(s string) *Signature {
 	x, y := String2BigIntTuple(s)
 	return &Signature{&x, &y}
 }
 
+// Deprecated: use ParsePublicKey.
 func PublicKeyFromString(s string) *ecdsa.PublicKey {
 	x, y := String2BigIntTuple(s)
 	return &ecdsa.PublicKey{
@@ -47,6 +128,8 @@ func PublicKeyFromString(s string) *ecdsa.PublicKey {
 	}
 }
 
+// Deprecated: the caller can pass a public key of another key.
+// Use PrivateKeyFromHex, which derives the public key from the private key.
 func PrivateKeyFromString(s string, publicKey *ecdsa.PublicKey) *ecdsa.PrivateKey {
 	b, _ := hex.DecodeString(s[:])
 	var bi big.Int
@@ -56,3 +139,17 @@ func PrivateKeyFromString(s string, publicKey *ecdsa.PublicKey) *ecdsa.PrivateKe
 		D:         &bi,
 	}
 }
+
+// PrivateKeyFromBytes restores a P-256 private key and derives its public key.
+// d must be in [1, n-1].
+func PrivateKeyFromBytes(d []byte) (*ecdsa.PrivateKey, error) {
+	return CurvePrivateKeyFromBytes(elliptic.P256(), d)
+}
+
+func PrivateKeyFromHex(s string) (*ecdsa.PrivateKey, error) {
+	d, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	return PrivateKeyFromBytes(d)
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+)
+
+// MESSAGE_PREFIX separates signed messages from transactions.
+// The signed bytes of a transaction are JSON and start with '{', while a message
+// starts with 0x19, so a message signature can never be replayed as a transaction.
+const MESSAGE_PREFIX = "\x19Blockchain Signed Message:\n"
+
+// MAX_MESSAGE_LENGTH limits the bytes of a message to sign.
+const MAX_MESSAGE_LENGTH = 4096
+
+var ErrMessageTooLong = fmt.Errorf("the message must be no longer than %d bytes", MAX_MESSAGE_LENGTH)
+
+// ValidateMessage checks the length of message in bytes, which MessageDigest hashes.
+// The Length rule of ozzo-validation counts runes, so it lets a message of multi-byte characters through.
+func ValidateMessage(message string) error {
+	if len(message) > MAX_MESSAGE_LENGTH {
+		return ErrMessageTooLong
+	}
+	return nil
+}
+
+// MessageDigest is SHA-256 of prefix || decimal length of the message || message.
+// The length keeps "ab"+"c" and "a"+"bc" of a longer prefix from colliding.
+func MessageDigest(message string) []byte {
+	h := sha256.New()
+	h.Write([]byte(MESSAGE_PREFIX))
+	h.Write([]byte(strconv.Itoa(len(message))))
+	h.Write([]byte(message))
+	return h.Sum(nil)
+}
+
+func SignMessage(scheme SignatureScheme, privateKey crypto.Signer, message string) []byte {
+	return scheme.Sign(privateKey, MessageDigest(message))
+}
+
+func VerifyMessage(scheme SignatureScheme, publicKey crypto.PublicKey, message string, signature []byte) bool {
+	return scheme.Verify(publicKey, MessageDigest(message), signature)
+}
+
+// ValidatorVoteMessage is what the key of a poa node signs to make the node vote for
+// adding (authorize) or removing publicKey. timestamp is UNIX time in ns, so that the request can't be replayed.
+func ValidatorVoteMessage(publicKey string, authorize bool, timestamp int64) string {
+	return fmt.Sprintf("validator vote %s authorize=%t timestamp=%d", publicKey, authorize, timestamp)
+}
+
+// ValidatorDiscardMessage is what the key of a poa node signs to make the node stop voting for publicKey.
+func ValidatorDiscardMessage(publicKey string, timestamp int64) string {
+	return fmt.Sprintf("validator discard %s timestamp=%d", publicKey, timestamp)
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"io"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Metrics are defined with promauto, so they are in prometheus.DefaultRegisterer
+// together with the Go runtime and process collectors.
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+
+const MetricsContentType = string(expfmt.FmtText)
+
+// WriteMetrics writes every metric of prometheus.DefaultGatherer in the text format.
+// The servers are fiber apps, so they write it themselves instead of mounting promhttp.Handler.
+func WriteMetrics(w io.Writer) error {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return err
+	}
+	enc := expfmt.NewEncoder(w, expfmt.FmtText)
+	for _, f := range families {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// A network profile keeps the coins of one network apart from the others: its addresses have
+// their own version byte, and its nodes and wallet listen on their own ports.
+// The versions are chosen so that the addresses of every scheme differ between the networks.
+const (
+	NETWORK_MAIN    = "main"
+	NETWORK_TEST    = "test"
+	NETWORK_REGTEST = "regtest"
+
+	ADDRESS_VERSION_TEST    byte = 0x6f // P-256 addresses start with "m" or "n", as on the Bitcoin testnet
+	ADDRESS_VERSION_REGTEST byte = 0x7b // P-256 addresses start with "r"
+
+	// the prefixes of the private keys in WIF, so that a key exported on one network can't be imported on another.
+	WIF_VERSION_MAIN    byte = 0x80 // as on the Bitcoin mainnet
+	WIF_VERSION_TEST    byte = 0xef // as on the Bitcoin testnet
+	WIF_VERSION_REGTEST byte = 0xfb
+)
+
+// NetworkNames are the names accepted by NetworkByName, for validation.In.
+var NetworkNames = []interface{}{NETWORK_MAIN, NETWORK_TEST, NETWORK_REGTEST}
+
+var ErrUnknownNetwork = errors.New("unknown network, one of main, test or regtest")
+
+type Network struct {
+	Name           string
+	AddressVersion byte // version byte of P-256 addresses, see SetAddressVersion
+	WIFVersion     byte // prefix of the private keys in WIF, see Use
+	WalletPort     int
+	NodePortStart  int // the nodes listen on NodePortStart..NodePortEnd and look for their neighbors there
+	NodePortEnd    int
+	Difficulty     int // default difficulty of the genesis params
+}
+
+var networks = map[string]*Network{
+	NETWORK_MAIN: {
+		Name:           NETWORK_MAIN,
+		AddressVersion: ADDRESS_VERSION_P256,
+		WIFVersion:     WIF_VERSION_MAIN,
+		WalletPort:     8000,
+		NodePortStart:  8001,
+		NodePortEnd:    8003,
+		Difficulty:     3,
+	},
+	NETWORK_TEST: {
+		Name:           NETWORK_TEST,
+		AddressVersion: ADDRESS_VERSION_TEST,
+		WIFVersion:     WIF_VERSION_TEST,
+		WalletPort:     18000,
+		NodePortStart:  18001,
+		NodePortEnd:    18003,
+		Difficulty:     2,
+	},
+	// regtest is a local network for tests, which mines a block almost instantly.
+	NETWORK_REGTEST: {
+		Name:           NETWORK_REGTEST,
+		AddressVersion: ADDRESS_VERSION_REGTEST,
+		WIFVersion:     WIF_VERSION_REGTEST,
+		WalletPort:     28000,
+		NodePortStart:  28001,
+		NodePortEnd:    28003,
+		Difficulty:     1,
+	},
+}
+
+// NetworkByName returns the main network for an empty name.
+func NetworkByName(name string) (*Network, error) {
+	if name == "" {
+		name = NETWORK_MAIN
+	}
+	n, ok := networks[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownNetwork, name)
+	}
+	return n, nil
+}
+
+// wifVersion is the WIF prefix of the network of this process.
+var wifVersion = WIF_VERSION_MAIN
+
+func WIFVersion() byte {
+	return wifVersion
+}
+
+// Use switches the addresses and the WIF of the process to this network.
+// Call it at startup, before any address is created or checked.
+func (n *Network) Use() {
+	SetAddressVersion(n.AddressVersion)
+	wifVersion = n.WIFVersion
+}
+
+// NodeURL is the API URL of the first node of the network on this machine.
+func (n *Network) NodeURL() string {
+	return "http://localhost:" + strconv.Itoa(n.NodePortStart) + "/v1"
+}
+
+// DataDir is the default directory of the files of the network, relative to the working directory,
+// so that the keys and the watched addresses of one network are never opened on another.
+func (n *Network) DataDir() string {
+	return filepath.Join("data", n.Name)
+}
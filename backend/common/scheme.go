@@ -0,0 +1,372 @@
+package common
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// A transaction names the scheme of its signature in signature_scheme.
+// The version byte of the sender address tells the scheme as well, and the two must agree,
+// so a key of one scheme can never be checked under the rules of another.
+const (
+	SCHEME_ECDSA_P256      = "ecdsa-p256"
+	SCHEME_ECDSA_SECP256K1 = "ecdsa-secp256k1"
+	SCHEME_ED25519         = "ed25519"
+
+	ADDRESS_VERSION_ED25519 byte = 0x21 // addresses start with "E"
+)
+
+// SignatureSchemes are the IDs accepted by SchemeByID, for validation.In.
+var SignatureSchemes = []interface{}{SCHEME_ECDSA_P256, SCHEME_ECDSA_SECP256K1, SCHEME_ED25519}
+
+var (
+	ErrUnknownScheme  = errors.New("unknown signature scheme, one of ecdsa-p256, ecdsa-secp256k1 or ed25519")
+	ErrSchemeMismatch = errors.New("signature_scheme does not match the blockchain address")
+	ErrCurveMismatch  = errors.New("curve does not match the signature scheme")
+)
+
+// SignatureScheme is everything that depends on the kind of key: key generation,
+// signing, verification, and the encodings of public keys, signatures and addresses.
+//
+// Keys are the types of the standard library: *ecdsa.PrivateKey and ed25519.PrivateKey
+// are both crypto.Signer, and their Public() is the crypto.PublicKey the other methods take.
+type SignatureScheme interface {
+	ID() string
+	GenerateKey() (crypto.Signer, error)
+	// PrivateKeyFromBytes restores a key from PrivateKeyBytes and derives its public key.
+	PrivateKeyFromBytes(b []byte) (crypto.Signer, error)
+	// PrivateKeyBytes are the 32 bytes to store or export.
+	PrivateKeyBytes(privateKey crypto.Signer) []byte
+	// Sign is deterministic: the same key and digest always produce the same signature.
+	Sign(privateKey crypto.Signer, digest []byte) []byte
+	Verify(publicKey crypto.PublicKey, digest, signature []byte) bool
+	// EncodePublicKey is the hex sent as sender_public_key. ParsePublicKey may accept other encodings.
+	EncodePublicKey(publicKey crypto.PublicKey) string
+	ParsePublicKey(s string) (crypto.PublicKey, error)
+	// ParseSignature decodes the hex of a signature into the bytes Verify takes.
+	ParseSignature(s string) ([]byte, error)
+	Address(publicKey crypto.PublicKey) string
+}
+
+// addressVersion is the version byte of P-256 addresses on the network of this process.
+// The ADDRESS_VERSION_* constants are the versions of the default network. The other schemes
+// keep their distance from the P-256 version, so that their addresses differ between networks too.
+var addressVersion = ADDRESS_VERSION_P256
+
+// SetAddressVersion switches the network of the addresses.
+// Call it at startup, before any address is created or checked.
+func SetAddressVersion(v byte) {
+	addressVersion = v
+}
+
+func AddressVersion() byte {
+	return addressVersion
+}
+
+// networkVersion maps the version of a scheme on the default network to the one on this network.
+func networkVersion(v byte) byte {
+	return v - ADDRESS_VERSION_P256 + addressVersion
+}
+
+var (
+	schemeP256      SignatureScheme = &ecdsaScheme{id: SCHEME_ECDSA_P256, curve: elliptic.P256(), version: ADDRESS_VERSION_P256}
+	schemeSecp256k1 SignatureScheme = &ecdsaScheme{id: SCHEME_ECDSA_SECP256K1, curve: secp256k1.S256(), version: ADDRESS_VERSION_SECP256K1}
+	schemeEd25519   SignatureScheme = ed25519Scheme{}
+)
+
+// SchemeByID returns ECDSA P-256 for an empty ID, the scheme of the transactions signed before the ID existed.
+func SchemeByID(id string) (SignatureScheme, error) {
+	switch id {
+	case "", SCHEME_ECDSA_P256:
+		return schemeP256, nil
+	case SCHEME_ECDSA_SECP256K1:
+		return schemeSecp256k1, nil
+	case SCHEME_ED25519:
+		return schemeEd25519, nil
+	}
+	return nil, ErrUnknownScheme
+}
+
+// SchemeByName picks a scheme by its ID, or the ECDSA scheme on the named curve.
+// It returns nil without an error when both are empty, so that the caller decides the default.
+func SchemeByName(id, curveName string) (SignatureScheme, error) {
+	var byCurve SignatureScheme
+	switch curveName {
+	case "":
+	case CURVE_P256:
+		byCurve = schemeP256
+	case CURVE_SECP256K1:
+		byCurve = schemeSecp256k1
+	default:
+		return nil, ErrUnknownCurve
+	}
+	if id == "" {
+		return byCurve, nil
+	}
+	scheme, err := SchemeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if byCurve != nil && byCurve != scheme {
+		return nil, ErrCurveMismatch
+	}
+	return scheme, nil
+}
+
+// SchemeOfAddress checks the length and the checksum of an address and returns the scheme of its version.
+// An address of another network is ErrInvalidAddress.
+func SchemeOfAddress(address string) (SignatureScheme, error) {
+	b := base58.Decode(address)
+	if len(b) != 25 || !bytes.Equal(addressChecksum(b[:21]), b[21:]) {
+		return nil, ErrInvalidAddress
+	}
+	switch b[0] {
+	case networkVersion(ADDRESS_VERSION_P256):
+		return schemeP256, nil
+	case networkVersion(ADDRESS_VERSION_SECP256K1):
+		return schemeSecp256k1, nil
+	case networkVersion(ADDRESS_VERSION_ED25519):
+		return schemeEd25519, nil
+	}
+	return nil, ErrInvalidAddress
+}
+
+// SchemeOfTransaction resolves the signature_scheme of a transaction from the sender address.
+// An empty ID means the scheme of the address, as sent by clients older than the ID.
+func SchemeOfTransaction(id, sender string) (SignatureScheme, error) {
+	scheme, err := SchemeOfAddress(sender)
+	if err != nil {
+		return nil, err
+	}
+	if id != "" && id != scheme.ID() {
+		if _, err := SchemeByID(id); err != nil {
+			return nil, err
+		}
+		return nil, ErrSchemeMismatch
+	}
+	return scheme, nil
+}
+
+// SchemeOfPublicKey tells the scheme from the type and the curve of a key.
+func SchemeOfPublicKey(publicKey crypto.PublicKey) (SignatureScheme, error) {
+	switch k := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if s := ecdsaSchemeOf(k.Curve); s != nil {
+			return s, nil
+		}
+	case ed25519.PublicKey:
+		return schemeEd25519, nil
+	}
+	return nil, ErrUnknownScheme
+}
+
+// ParseAnyPublicKey parses a public key of a scheme unknown to the caller, e.g. the producer of a block.
+// Only the encoding of EncodePublicKey is accepted, so that every key has exactly one string:
+// ed25519 keys are 32 bytes, and an ECDSA point is on only one of P-256 and secp256k1.
+func ParseAnyPublicKey(s string) (SignatureScheme, crypto.PublicKey, error) {
+	for _, scheme := range []SignatureScheme{schemeP256, schemeSecp256k1, schemeEd25519} {
+		if k, err := scheme.ParsePublicKey(s); err == nil && scheme.EncodePublicKey(k) == s {
+			return scheme, k, nil
+		}
+	}
+	return nil, nil, ErrInvalidPublicKey
+}
+
+func ecdsaSchemeOf(curve elliptic.Curve) SignatureScheme {
+	switch curve {
+	case elliptic.P256():
+		return schemeP256
+	case secp256k1.S256():
+		return schemeSecp256k1
+	}
+	return nil
+}
+
+// hash160Address is version || RIPEMD-160(SHA-256(b)) || checksum in base58.
+func hash160Address(version byte, b ...[]byte) string {
+	h := sha256.New()
+	for _, p := range b {
+		h.Write(p)
+	}
+	r := ripemd160.New()
+	r.Write(h.Sum(nil))
+	payload := append([]byte{version}, r.Sum(nil)...)
+	return base58.Encode(append(payload, addressChecksum(payload)...))
+}
+
+// ecdsaScheme signs with RFC 6979 nonces and low S, see SignDigest.
+// Signatures are 64 bytes of R || S; ParseSignature also accepts DER.
+type ecdsaScheme struct {
+	id      string
+	curve   elliptic.Curve
+	version byte
+}
+
+func (s *ecdsaScheme) ID() string {
+	return s.id
+}
+
+func (s *ecdsaScheme) GenerateKey() (crypto.Signer, error) {
+	return ecdsa.GenerateKey(s.curve, rand.Reader)
+}
+
+func (s *ecdsaScheme) PrivateKeyFromBytes(b []byte) (crypto.Signer, error) {
+	return CurvePrivateKeyFromBytes(s.curve, b)
+}
+
+func (s *ecdsaScheme) PrivateKeyBytes(privateKey crypto.Signer) []byte {
+	k, ok := privateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil
+	}
+	return k.D.FillBytes(make([]byte, 32))
+}
+
+func (s *ecdsaScheme) Sign(privateKey crypto.Signer, digest []byte) []byte {
+	k, ok := privateKey.(*ecdsa.PrivateKey)
+	if !ok || k.Curve != s.curve {
+		return nil
+	}
+	return SignDigest(k, digest).Bytes()
+}
+
+func (s *ecdsaScheme) Verify(publicKey crypto.PublicKey, digest, signature []byte) bool {
+	k, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok || k.Curve != s.curve || len(signature) != 64 {
+		return false
+	}
+	return VerifyDigest(k, digest, SignatureFromBytes(signature))
+}
+
+// EncodePublicKey is X || Y, the encoding every client understands.
+func (s *ecdsaScheme) EncodePublicKey(publicKey crypto.PublicKey) string {
+	k, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return ""
+	}
+	return PublicKeyString(k)
+}
+
+func (s *ecdsaScheme) ParsePublicKey(str string) (crypto.PublicKey, error) {
+	return ParseCurvePublicKey(s.curve, str)
+}
+
+func (s *ecdsaScheme) ParseSignature(str string) ([]byte, error) {
+	sig, err := ParseSignature(str)
+	if err != nil {
+		return nil, err
+	}
+	// R and S out of range don't fit in 64 bytes; Verify rejects them anyway.
+	if sig.R.BitLen() > 256 || sig.S.BitLen() > 256 {
+		return nil, ErrInvalidSignature
+	}
+	return sig.Bytes(), nil
+}
+
+// Address keeps the original derivation for P-256: SHA-256 of X || Y (without leading zeros),
+// then RIPEMD-160. secp256k1 hashes the 33 bytes compressed key like Bitcoin does,
+// so the same hash160 as standard tooling comes out; only the version byte differs.
+// https://en.bitcoin.it/wiki/Technical_background_of_version_1_Bitcoin_addresses
+func (s *ecdsaScheme) Address(publicKey crypto.PublicKey) string {
+	k, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return ""
+	}
+	if s.version == ADDRESS_VERSION_SECP256K1 {
+		return hash160Address(networkVersion(s.version), CompressPublicKey(k))
+	}
+	return hash160Address(networkVersion(s.version), k.X.Bytes(), k.Y.Bytes())
+}
+
+// ed25519Scheme signs the 32 bytes digest as the message of pure Ed25519 (RFC 8032).
+// Keys are 32 bytes, and so is the seed kept as the private key; signatures are 64 bytes.
+// https://www.rfc-editor.org/rfc/rfc8032
+type ed25519Scheme struct{}
+
+func (ed25519Scheme) ID() string {
+	return SCHEME_ED25519
+}
+
+func (ed25519Scheme) GenerateKey() (crypto.Signer, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+func (ed25519Scheme) PrivateKeyFromBytes(b []byte) (crypto.Signer, error) {
+	if len(b) != ed25519.SeedSize {
+		return nil, ErrInvalidPrivateKey
+	}
+	return ed25519.NewKeyFromSeed(b), nil
+}
+
+func (ed25519Scheme) PrivateKeyBytes(privateKey crypto.Signer) []byte {
+	k, ok := privateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil
+	}
+	return k.Seed()
+}
+
+func (ed25519Scheme) Sign(privateKey crypto.Signer, digest []byte) []byte {
+	k, ok := privateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil
+	}
+	return ed25519.Sign(k, digest)
+}
+
+// Verify rejects a non-canonical S, so a signature can't be malleated either.
+func (ed25519Scheme) Verify(publicKey crypto.PublicKey, digest, signature []byte) bool {
+	k, ok := publicKey.(ed25519.PublicKey)
+	if !ok || len(k) != ed25519.PublicKeySize || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(k, digest, signature)
+}
+
+func (ed25519Scheme) EncodePublicKey(publicKey crypto.PublicKey) string {
+	k, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return ""
+	}
+	return hex.EncodeToString(k)
+}
+
+func (ed25519Scheme) ParsePublicKey(s string) (crypto.PublicKey, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: ed25519 keys are 64 hex characters", ErrInvalidPublicKey)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+func (ed25519Scheme) ParseSignature(s string) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != ed25519.SignatureSize {
+		return nil, ErrInvalidSignature
+	}
+	return b, nil
+}
+
+func (ed25519Scheme) Address(publicKey crypto.PublicKey) string {
+	k, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return ""
+	}
+	return hash160Address(networkVersion(ADDRESS_VERSION_ED25519), k)
+}
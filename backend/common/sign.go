@@ -0,0 +1,265 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+// Reference implementation of transaction signing for clients.
+//
+// 1. POST /v1/transactions/prepare on the wallet server returns the message and its digest.
+//    The message has the next nonce of the sender, see TransactionMessage.
+// 2. The client signs the digest locally with the Sign of its SignatureScheme
+//    (or checks it with TransactionDigest first). For ECDSA that is SignDigest.
+// 3. The client sends only the public key, the hex of the signature and the scheme ID
+//    to POST /v1/transactions. ECDSA signatures may also be DERString().
+//
+// Batch payments work the same way with /v1/transactions/batch/prepare and /v1/transactions/batch.
+//
+// Signatures of every scheme are deterministic (RFC 6979 for ECDSA), so the same key and
+// transaction always produce the same bytes as Transaction.GenerateSignature on the wallet server.
+
+// MAX_TRANSACTION_OUTPUTS limits the recipients of one batch transaction.
+const MAX_TRANSACTION_OUTPUTS = 100
+
+// Types of transactions that don't pay a recipient. An empty type is a payment.
+const (
+	TRANSACTION_TYPE_STAKE   = "stake"   // locks value of the sender as stake
+	TRANSACTION_TYPE_UNSTAKE = "unstake" // unlocks value of the stake after the lock-up period
+	TRANSACTION_TYPE_SLASH   = "slash"   // burns the stake of a producer who signed two blocks at the same height
+)
+
+// StakeTransactionTypes are the types a client can sign.
+var StakeTransactionTypes = []interface{}{TRANSACTION_TYPE_STAKE, TRANSACTION_TYPE_UNSTAKE}
+
+var (
+	ErrNoOutputs      = errors.New("a batch transaction needs at least one output")
+	ErrTooManyOutputs = fmt.Errorf("a batch transaction has at most %d outputs", MAX_TRANSACTION_OUTPUTS)
+	ErrInvalidOutput  = errors.New("every output needs a recipient and a positive value")
+)
+
+// TransactionMessage is the part of a transaction covered by the signature.
+// The JSON field order must not change; it defines the signed bytes.
+//
+// A batch transaction pays several recipients at once. Its recipient is empty,
+// its value is the sum of the outputs, and it is signed once as a whole.
+// A stake or unstake transaction has a type and no recipient.
+//
+// Nonce counts the transactions of the sender: the first one has 0, the next 1, and so on.
+// Nodes accept only the next nonce of the sender, so a signed transaction can't be sent twice,
+// and two transactions with the same fields still have different IDs.
+type TransactionMessage struct {
+	SenderBlockchainAddress    string               `json:"sender_blockchain_address"`
+	RecipientBlockchainAddress string               `json:"recipient_blockchain_address"`
+	Value                      float64              `json:"value"`
+	Outputs                    []*TransactionOutput `json:"outputs,omitempty"`
+	Type                       string               `json:"type,omitempty"`
+	Nonce                      uint64               `json:"nonce"`
+}
+
+type TransactionOutput struct {
+	RecipientBlockchainAddress string  `json:"recipient_blockchain_address"`
+	Value                      float64 `json:"value"`
+}
+
+func NewTransactionMessage(sender, recipient string, value float64, nonce uint64) *TransactionMessage {
+	return &TransactionMessage{
+		SenderBlockchainAddress:    sender,
+		RecipientBlockchainAddress: recipient,
+		Value:                      value,
+		Nonce:                      nonce,
+	}
+}
+
+func NewBatchTransactionMessage(sender string, outputs []*TransactionOutput, nonce uint64) *TransactionMessage {
+	return &TransactionMessage{
+		SenderBlockchainAddress: sender,
+		Value:                   OutputsTotal(outputs),
+		Outputs:                 outputs,
+		Nonce:                   nonce,
+	}
+}
+
+// NewStakeTransactionMessage stakes or unstakes value of the sender, depending on typ.
+func NewStakeTransactionMessage(sender, typ string, value float64, nonce uint64) *TransactionMessage {
+	return &TransactionMessage{
+		SenderBlockchainAddress: sender,
+		Value:                   value,
+		Type:                    typ,
+		Nonce:                   nonce,
+	}
+}
+
+// OutputsTotal adds the values in order, so that every node gets exactly the same float.
+func OutputsTotal(outputs []*TransactionOutput) float64 {
+	total := 0.0
+	for _, o := range outputs {
+		total += o.Value
+	}
+	return total
+}
+
+func ValidateOutputs(outputs []*TransactionOutput) error {
+	if len(outputs) == 0 {
+		return ErrNoOutputs
+	}
+	if len(outputs) > MAX_TRANSACTION_OUTPUTS {
+		return ErrTooManyOutputs
+	}
+	for i, o := range outputs {
+		if o == nil || o.RecipientBlockchainAddress == "" || !(o.Value > 0) {
+			return ErrInvalidOutput
+		}
+		if err := ValidateAddress(o.RecipientBlockchainAddress); err != nil {
+			return fmt.Errorf("outputs[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (m *TransactionMessage) Bytes() []byte {
+	b, _ := json.Marshal(m)
+	return b
+}
+
+func (m *TransactionMessage) Digest() []byte {
+	h := sha256.Sum256(m.Bytes())
+	return h[:]
+}
+
+// ID identifies a transaction by the digest of its signed fields in hex.
+// The signature is not part of it, so re-encoding or malleating the signature can't change the ID.
+func (m *TransactionMessage) ID() string {
+	return hex.EncodeToString(m.Digest())
+}
+
+func TransactionDigest(sender, recipient string, value float64, nonce uint64) []byte {
+	return NewTransactionMessage(sender, recipient, value, nonce).Digest()
+}
+
+// SignDigest signs a digest with a nonce derived from the key and the digest (RFC 6979).
+// The signature is normalized to low S.
+// https://www.rfc-editor.org/rfc/rfc6979#section-3.2
+func SignDigest(privateKey *ecdsa.PrivateKey, digest []byte) *Signature {
+	params := privateKey.Curve.Params()
+	n := params.N
+	e := hashToInt(digest, n)
+	nonces := newNonceGenerator(privateKey.D, digest, n, sha256.New)
+	for {
+		k := nonces.next()
+		x, _ := privateKey.Curve.ScalarBaseMult(k.Bytes())
+		r := new(big.Int).Mod(x, n)
+		if r.Sign() == 0 {
+			continue
+		}
+		// s = k^-1 (e + r*d) mod n
+		s := new(big.Int).Mul(r, privateKey.D)
+		s.Add(s, e)
+		s.Mul(s, new(big.Int).ModInverse(k, n))
+		s.Mod(s, n)
+		if s.Sign() == 0 {
+			continue
+		}
+		sig := &Signature{R: r, S: s}
+		if !sig.IsLowS(privateKey.Curve) {
+			sig.S.Sub(n, sig.S)
+		}
+		return sig
+	}
+}
+
+// VerifyDigest rejects a signature with a high S, which SignDigest never creates.
+func VerifyDigest(publicKey *ecdsa.PublicKey, digest []byte, s *Signature) bool {
+	if publicKey == nil || s == nil || s.R == nil || s.S == nil {
+		return false
+	}
+	if !s.IsLowS(publicKey.Curve) {
+		return false
+	}
+	return ecdsa.Verify(publicKey, digest, s.R, s.S)
+}
+
+// hashToInt is bits2int of RFC 6979, the same conversion as crypto/ecdsa.
+func hashToInt(digest []byte, n *big.Int) *big.Int {
+	orderBits := n.BitLen()
+	orderBytes := (orderBits + 7) / 8
+	if len(digest) > orderBytes {
+		digest = digest[:orderBytes]
+	}
+	ret := new(big.Int).SetBytes(digest)
+	if excess := len(digest)*8 - orderBits; excess > 0 {
+		ret.Rsh(ret, uint(excess))
+	}
+	return ret
+}
+
+// nonceGenerator is the HMAC_DRBG of RFC 6979 section 3.2.
+type nonceGenerator struct {
+	n    *big.Int
+	hash func() hash.Hash
+	k    []byte
+	v    []byte
+	used bool
+}
+
+func newNonceGenerator(d *big.Int, digest []byte, n *big.Int, h func() hash.Hash) *nonceGenerator {
+	rolen := (n.BitLen() + 7) / 8
+	x := intToOctets(d, rolen)
+	h1 := intToOctets(new(big.Int).Mod(hashToInt(digest, n), n), rolen)
+
+	size := h().Size()
+	g := &nonceGenerator{n: n, hash: h, k: make([]byte, size), v: make([]byte, size)}
+	for i := range g.v {
+		g.v[i] = 0x01
+	}
+	g.k = g.mac(g.k, g.v, []byte{0x00}, x, h1)
+	g.v = g.mac(g.k, g.v)
+	g.k = g.mac(g.k, g.v, []byte{0x01}, x, h1)
+	g.v = g.mac(g.k, g.v)
+	return g
+}
+
+func (g *nonceGenerator) mac(key []byte, data ...[]byte) []byte {
+	m := hmac.New(g.hash, key)
+	for _, d := range data {
+		m.Write(d)
+	}
+	return m.Sum(nil)
+}
+
+func (g *nonceGenerator) next() *big.Int {
+	rolen := (g.n.BitLen() + 7) / 8
+	for {
+		if g.used {
+			g.k = g.mac(g.k, g.v, []byte{0x00})
+			g.v = g.mac(g.k, g.v)
+		}
+		g.used = true
+		var t []byte
+		for len(t) < rolen {
+			g.v = g.mac(g.k, g.v)
+			t = append(t, g.v...)
+		}
+		k := hashToInt(t, g.n)
+		if k.Sign() > 0 && k.Cmp(g.n) < 0 {
+			return k
+		}
+	}
+}
+
+func intToOctets(v *big.Int, rolen int) []byte {
+	b := v.Bytes()
+	if len(b) >= rolen {
+		return b[len(b)-rolen:]
+	}
+	out := make([]byte, rolen)
+	copy(out[rolen-len(b):], b)
+	return out
+}
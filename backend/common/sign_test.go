@@ -0,0 +1,86 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+func hexInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("bad hex %q", s)
+	}
+	return v
+}
+
+// RFC 6979 A.2.5: ECDSA, 256 bits (prime field), with SHA-256.
+// https://www.rfc-editor.org/rfc/rfc6979#appendix-A.2.5
+func TestSignDigestRFC6979P256(t *testing.T) {
+	curve := elliptic.P256()
+	privateKey := &ecdsa.PrivateKey{D: hexInt(t, "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")}
+	privateKey.Curve = curve
+	privateKey.X, privateKey.Y = curve.ScalarBaseMult(privateKey.D.Bytes())
+	if want := hexInt(t, "60FED4BA255A9D31C961EB74C6356D68C049B8923B61FA6CE669622E60F29FB6"); privateKey.X.Cmp(want) != 0 {
+		t.Fatalf("Ux = %x, want %x", privateKey.X, want)
+	}
+
+	tests := []struct {
+		message string
+		k, r, s string
+	}{
+		{
+			message: "sample",
+			k:       "A6E3C57DD01ABE90086538398355DD4C3B17AA873382B0F24D6129493D8AAD60",
+			r:       "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+			s:       "F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8",
+		},
+		{
+			message: "test",
+			k:       "D16B6AE827F17175E040871A1C7EC3500192C4C92677336EC2537ACAEE0008E0",
+			r:       "F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367",
+			s:       "019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083",
+		},
+	}
+	n := curve.Params().N
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			digest := sha256.Sum256([]byte(tt.message))
+			if k := newNonceGenerator(privateKey.D, digest[:], n, sha256.New).next(); k.Cmp(hexInt(t, tt.k)) != 0 {
+				t.Errorf("k = %X, want %s", k, tt.k)
+			}
+
+			sig := SignDigest(privateKey, digest[:])
+			if sig.R.Cmp(hexInt(t, tt.r)) != 0 {
+				t.Errorf("r = %X, want %s", sig.R, tt.r)
+			}
+			// SignDigest normalizes to low S, so the S of the vector may come out as N-S.
+			wantS := hexInt(t, tt.s)
+			if !(&Signature{R: sig.R, S: wantS}).IsLowS(curve) {
+				wantS.Sub(n, wantS)
+			}
+			if sig.S.Cmp(wantS) != 0 {
+				t.Errorf("s = %X, want %X", sig.S, wantS)
+			}
+			if !VerifyDigest(&privateKey.PublicKey, digest[:], sig) {
+				t.Error("VerifyDigest rejected the signature")
+			}
+		})
+	}
+}
+
+func TestVerifyDigestRejectsHighS(t *testing.T) {
+	privateKey, err := PrivateKeyFromBytes(sha256.New().Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("sample"))
+	sig := SignDigest(privateKey, digest[:])
+	high := &Signature{R: sig.R, S: new(big.Int).Sub(privateKey.Curve.Params().N, sig.S)}
+	if VerifyDigest(&privateKey.PublicKey, digest[:], high) {
+		t.Error("VerifyDigest accepted a high S")
+	}
+}
@@ -0,0 +1,182 @@
+// Package addressbook keeps named contacts, so that a transaction can be sent to a name
+// instead of a pasted blockchain address.
+package addressbook
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/wallet/filestore"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
+)
+
+var (
+	ErrNotFound      = errors.New("contact not found")
+	ErrAlreadyExists = errors.New("contact with the same name already exists")
+)
+
+type Contact struct {
+	Name              string    `json:"name"`
+	BlockchainAddress string    `json:"blockchain_address"`
+	Notes             string    `json:"notes"`
+	Tags              []string  `json:"tags"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func (c *Contact) HasTag(tag string) bool {
+	for _, t := range c.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Store keeps all contacts in one JSON file. The name is the ID of a contact.
+type Store struct {
+	path string
+
+	mux      sync.Mutex
+	contacts []*Contact
+}
+
+func New(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := filestore.ReadJSON(path, &s.contacts); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create saves a contact. The checksum of the address is checked here and again when sending.
+func (s *Store) Create(name, address, notes string, tags []string) (*Contact, error) {
+	if err := model.ValidateAddress(address); err != nil {
+		return nil, err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.find(name) != nil {
+		return nil, ErrAlreadyExists
+	}
+	now := time.Now().UTC()
+	c := &Contact{
+		Name:              name,
+		BlockchainAddress: address,
+		Notes:             notes,
+		Tags:              normalizeTags(tags),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := s.save(append(s.contacts, c)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// List returns the contacts sorted by name. An empty tag returns all of them.
+func (s *Store) List(tag string) []*Contact {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	contacts := make([]*Contact, 0, len(s.contacts))
+	for _, c := range s.contacts {
+		if tag == "" || c.HasTag(tag) {
+			contacts = append(contacts, c)
+		}
+	}
+	sort.Slice(contacts, func(i, j int) bool { return contacts[i].Name < contacts[j].Name })
+	return contacts
+}
+
+func (s *Store) Find(name string) (*Contact, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if c := s.find(name); c != nil {
+		return c, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *Store) find(name string) *Contact {
+	for _, c := range s.contacts {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Update replaces the contact. newName renames it.
+func (s *Store) Update(name, newName, address, notes string, tags []string) (*Contact, error) {
+	if err := model.ValidateAddress(address); err != nil {
+		return nil, err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	old := s.find(name)
+	if old == nil {
+		return nil, ErrNotFound
+	}
+	if newName != name && s.find(newName) != nil {
+		return nil, ErrAlreadyExists
+	}
+	updated := &Contact{
+		Name:              newName,
+		BlockchainAddress: address,
+		Notes:             notes,
+		Tags:              normalizeTags(tags),
+		CreatedAt:         old.CreatedAt,
+		UpdatedAt:         time.Now().UTC(),
+	}
+	contacts := make([]*Contact, 0, len(s.contacts))
+	for _, c := range s.contacts {
+		if c == old {
+			c = updated
+		}
+		contacts = append(contacts, c)
+	}
+	if err := s.save(contacts); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *Store) Delete(name string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	contacts := make([]*Contact, 0, len(s.contacts))
+	for _, c := range s.contacts {
+		if c.Name != name {
+			contacts = append(contacts, c)
+		}
+	}
+	if len(contacts) == len(s.contacts) {
+		return ErrNotFound
+	}
+	return s.save(contacts)
+}
+
+// save writes the file first, so that memory and disk never disagree.
+func (s *Store) save(contacts []*Contact) error {
+	if err := filestore.WriteJSON(s.path, contacts); err != nil {
+		return err
+	}
+	s.contacts = contacts
+	return nil
+}
+
+// normalizeTags drops empty and duplicated tags.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		normalized = append(normalized, t)
+	}
+	return normalized
+}
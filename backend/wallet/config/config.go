@@ -0,0 +1,234 @@
+// Package config loads the wallet server settings.
+// 優先順位: default < config file (JSON) < 環境変数 < flag
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+type Config struct {
+	Network       string   `json:"network"`        // main, test or regtest
+	ListenAddress string   `json:"listen_address"` // the wallet port of the network if empty
+	Nodes         []string `json:"nodes"`          // blockchain node URLs, e.g. http://localhost:8001/v1. The first node of the network if empty
+	Timeout       Duration `json:"timeout"`
+	Retries       int      `json:"retries"` // retries per node before failing over to the next one. A POST is retried only when it was not sent
+	RetryInterval Duration `json:"retry_interval"`
+	NodeCooldown  Duration `json:"node_cooldown"` // how long a failed node is skipped
+	KeystoreDir   string   `json:"keystore_dir"`  // directory of the encrypted key files. data/<network>/keystore if empty
+	WatchFile     string   `json:"watch_file"`    // watch-only wallets. data/<network>/watch.json if empty
+	AddressBook   string   `json:"address_book"`  // file of the contacts. data/<network>/addressbook.json if empty
+}
+
+// Default leaves the listen address, the nodes and the data files to the network, see applyNetwork.
+func Default() *Config {
+	return &Config{
+		Network:       common.NETWORK_MAIN,
+		Timeout:       Duration(5 * time.Second),
+		Retries:       1,
+		RetryInterval: Duration(500 * time.Millisecond),
+		NodeCooldown:  Duration(30 * time.Second),
+	}
+}
+
+// Duration is a time.Duration written as "5s" in the config file.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// Load reads the command line flags of the wallet server.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("wallet", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("WALLET_CONFIG"), "path to a JSON config file")
+	network := fs.String("network", "", "network of the wallet: main, test or regtest")
+	listen := fs.String("listen", "", "listen address of the wallet server")
+	nodes := fs.String("nodes", "", "comma separated blockchain node URLs")
+	timeout := fs.Duration("timeout", 0, "timeout of a request to a blockchain node")
+	retries := fs.Int("retries", 0, "retries per blockchain node")
+	retryInterval := fs.Duration("retry-interval", 0, "wait between retries")
+	cooldown := fs.Duration("node-cooldown", 0, "how long a failed blockchain node is skipped")
+	keystoreDir := fs.String("keystore", "", "directory of the encrypted key files")
+	watchFile := fs.String("watch-file", "", "file of the watch-only wallets")
+	addressBook := fs.String("address-book", "", "file of the address book")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := Default()
+	if *path != "" {
+		if err := c.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "network":
+			c.Network = *network
+		case "listen":
+			c.ListenAddress = *listen
+		case "nodes":
+			c.Nodes = splitList(*nodes)
+		case "timeout":
+			c.Timeout = Duration(*timeout)
+		case "retries":
+			c.Retries = *retries
+		case "retry-interval":
+			c.RetryInterval = Duration(*retryInterval)
+		case "node-cooldown":
+			c.NodeCooldown = Duration(*cooldown)
+		case "keystore":
+			c.KeystoreDir = *keystoreDir
+		case "watch-file":
+			c.WatchFile = *watchFile
+		case "address-book":
+			c.AddressBook = *addressBook
+		}
+	})
+	if err := c.applyNetwork(); err != nil {
+		return nil, err
+	}
+	return c, c.Validate()
+}
+
+// applyNetwork fills the listen address and the nodes with the default ports of the network,
+// and the data files with the paths under the data directory of the network.
+func (c *Config) applyNetwork() error {
+	n, err := common.NetworkByName(c.Network)
+	if err != nil {
+		return err
+	}
+	c.Network = n.Name
+	if c.ListenAddress == "" {
+		c.ListenAddress = ":" + strconv.Itoa(n.WalletPort)
+	}
+	if len(c.Nodes) == 0 {
+		c.Nodes = []string{n.NodeURL()}
+	}
+	if c.KeystoreDir == "" {
+		c.KeystoreDir = filepath.Join(n.DataDir(), "keystore")
+	}
+	if c.WatchFile == "" {
+		c.WatchFile = filepath.Join(n.DataDir(), "watch.json")
+	}
+	if c.AddressBook == "" {
+		c.AddressBook = filepath.Join(n.DataDir(), "addressbook.json")
+	}
+	return nil
+}
+
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	if v, ok := os.LookupEnv("WALLET_NETWORK"); ok {
+		c.Network = v
+	}
+	if v, ok := os.LookupEnv("WALLET_LISTEN_ADDRESS"); ok {
+		c.ListenAddress = v
+	}
+	if v, ok := os.LookupEnv("WALLET_NODES"); ok {
+		c.Nodes = splitList(v)
+	}
+	if v, ok := os.LookupEnv("WALLET_KEYSTORE_DIR"); ok {
+		c.KeystoreDir = v
+	}
+	if v, ok := os.LookupEnv("WALLET_WATCH_FILE"); ok {
+		c.WatchFile = v
+	}
+	if v, ok := os.LookupEnv("WALLET_ADDRESS_BOOK"); ok {
+		c.AddressBook = v
+	}
+	durations := map[string]*Duration{
+		"WALLET_TIMEOUT":        &c.Timeout,
+		"WALLET_RETRY_INTERVAL": &c.RetryInterval,
+		"WALLET_NODE_COOLDOWN":  &c.NodeCooldown,
+	}
+	for key, d := range durations {
+		if v, ok := os.LookupEnv(key); ok {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			*d = Duration(parsed)
+		}
+	}
+	if v, ok := os.LookupEnv("WALLET_RETRIES"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("WALLET_RETRIES: %w", err)
+		}
+		c.Retries = n
+	}
+	return nil
+}
+
+func (c *Config) Validate() error {
+	if len(c.Nodes) == 0 {
+		return fmt.Errorf("at least one blockchain node is required")
+	}
+	if c.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	if c.KeystoreDir == "" {
+		return fmt.Errorf("keystore_dir is required")
+	}
+	if c.WatchFile == "" {
+		return fmt.Errorf("watch_file is required")
+	}
+	if c.AddressBook == "" {
+		return fmt.Errorf("address_book is required")
+	}
+	for i, n := range c.Nodes {
+		c.Nodes[i] = strings.TrimSuffix(n, "/")
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
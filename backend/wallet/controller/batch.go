@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
+)
+
+const mimeTextCSV = "text/csv"
+
+// parseBatchRequest reads a JSON body, or a CSV body of payouts with the other fields in the query string.
+func parseBatchRequest(c *fiber.Ctx, r interface{}, outputs *[]*model.Payout) error {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), mimeTextCSV) {
+		return c.BodyParser(r)
+	}
+	if err := c.QueryParser(r); err != nil {
+		return err
+	}
+	payouts, err := model.ParsePayoutsCSV(bytes.NewReader(c.Body()))
+	if err != nil {
+		return err
+	}
+	*outputs = payouts
+	return nil
+}
+
+// resolveOutputs turns contact names into addresses and checks every address.
+func resolveOutputs(payouts []*model.Payout) ([]*common.TransactionOutput, error) {
+	outputs := make([]*common.TransactionOutput, 0, len(payouts))
+	for i, p := range payouts {
+		recipient, err := resolveRecipient(p.RecipientBlockchainAddress, p.RecipientContact)
+		if err != nil {
+			return nil, fmt.Errorf("outputs[%d]: %w", i, err)
+		}
+		outputs = append(outputs, &common.TransactionOutput{
+			RecipientBlockchainAddress: recipient,
+			Value:                      p.Value,
+		})
+	}
+	if err := common.ValidateOutputs(outputs); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// prepareBatchTransaction returns the message of a batch transaction to sign locally.
+func prepareBatchTransaction(c *fiber.Ctx) error {
+	var t model.PrepareBatchTransactionRequest
+	if err := parseBatchRequest(c, &t, &t.Outputs); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	if err := t.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	publicKey, err := common.ParsePublicKeyForAddress(t.SenderPublicKey, t.SenderBlockchainAddress)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	if model.AddressFromPublicKey(publicKey) != t.SenderBlockchainAddress {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse("sender_public_key does not match sender_blockchain_address"))
+	}
+	outputs, err := resolveOutputs(t.Outputs)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+
+	nonce, err := fetchNonce(t.SenderBlockchainAddress)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+	}
+	m := common.NewBatchTransactionMessage(t.SenderBlockchainAddress, outputs, nonce)
+	return c.JSON(model.PrepareTransactionResponse{
+		ID:          m.ID(),
+		Transaction: m,
+		Message:     string(m.Bytes()),
+		Digest:      hex.EncodeToString(m.Digest()),
+	})
+}
+
+// createBatchTransaction sends one transaction that pays every output.
+// The node accepts it only if the sender can pay the total.
+func createBatchTransaction(c *fiber.Ctx) error {
+	var t model.BatchTransactionRequest
+	if err := parseBatchRequest(c, &t, &t.Outputs); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	if err := t.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	outputs, err := resolveOutputs(t.Outputs)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+
+	if t.SenderWallet != "" {
+		w, err := signingWallet(c, t.SenderWallet)
+		if err != nil || w == nil {
+			return err
+		}
+		nonce, err := fetchNonce(w.BlockchainAddress())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+		}
+		transaction := model.NewBatchTransaction(w.PrivateKey(), w.PublicKey(), w.BlockchainAddress(), outputs, nonce)
+		return sendTransaction(c, &model.BlockchainTransactionRequest{
+			SenderBlockchainAddress: w.BlockchainAddress(),
+			SenderPublicKey:         w.PublicKeyStr(),
+			Value:                   transaction.Value,
+			Outputs:                 outputs,
+			Signature:               transaction.GenerateSignature(),
+			SignatureScheme:         w.Scheme().ID(),
+			Nonce:                   nonce,
+		})
+	}
+
+	m := common.NewBatchTransactionMessage(t.SenderBlockchainAddress, outputs, t.Nonce)
+	scheme, err := verifyClientSignature(c, t.SignatureScheme, t.SenderBlockchainAddress, t.SenderPublicKey, t.Signature, m.Digest())
+	if err != nil || scheme == nil {
+		return err
+	}
+	return sendTransaction(c, &model.BlockchainTransactionRequest{
+		SenderBlockchainAddress: t.SenderBlockchainAddress,
+		SenderPublicKey:         t.SenderPublicKey,
+		Value:                   m.Value,
+		Outputs:                 outputs,
+		Signature:               t.Signature,
+		SignatureScheme:         scheme.ID(),
+		Nonce:                   t.Nonce,
+	})
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/addressbook"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
+)
+
+var contacts *addressbook.Store
+
+// contactError maps address book errors to HTTP statuses.
+func contactError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, addressbook.ErrNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, addressbook.ErrAlreadyExists):
+		status = fiber.StatusConflict
+	case errors.Is(err, model.ErrInvalidAddress):
+		status = fiber.StatusBadRequest
+	}
+	return c.Status(status).JSON(common.NewResponse(err.Error()))
+}
+
+// contactName returns the :name path parameter, which may contain escaped characters.
+func contactName(c *fiber.Ctx) string {
+	name, err := url.PathUnescape(c.Params("name"))
+	if err != nil {
+		return c.Params("name")
+	}
+	return name
+}
+
+func createContact(c *fiber.Ctx) error {
+	var r model.ContactRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	contact, err := contacts.Create(r.Name, r.BlockchainAddress, r.Notes, r.Tags)
+	if err != nil {
+		return contactError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(contact)
+}
+
+// listContacts returns all contacts, or only the ones with ?tag=.
+func listContacts(c *fiber.Ctx) error {
+	return c.JSON(contacts.List(c.Query("tag")))
+}
+
+func getContact(c *fiber.Ctx) error {
+	contact, err := contacts.Find(contactName(c))
+	if err != nil {
+		return contactError(c, err)
+	}
+	return c.JSON(contact)
+}
+
+// updateContact replaces the contact. A different name in the body renames it.
+func updateContact(c *fiber.Ctx) error {
+	var r model.ContactRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	contact, err := contacts.Update(contactName(c), r.Name, r.BlockchainAddress, r.Notes, r.Tags)
+	if err != nil {
+		return contactError(c, err)
+	}
+	return c.JSON(contact)
+}
+
+func deleteContact(c *fiber.Ctx) error {
+	if err := contacts.Delete(contactName(c)); err != nil {
+		return contactError(c, err)
+	}
+	return c.JSON(common.NewResponse("contact deleted"))
+}
+
+// resolveRecipient returns the recipient address of a transaction request.
+// A contact name is looked up in the address book. Either way the checksum is checked
+// again here, because the file may have been edited since the contact was saved.
+func resolveRecipient(address, contactName string) (string, error) {
+	if contactName != "" {
+		contact, err := contacts.Find(contactName)
+		if err != nil {
+			return "", fmt.Errorf("recipient_contact %q: %w", contactName, err)
+		}
+		if address != "" && address != contact.BlockchainAddress {
+			return "", fmt.Errorf("recipient_blockchain_address does not match the address of contact %q", contactName)
+		}
+		address = contact.BlockchainAddress
+	}
+	if err := model.ValidateAddress(address); err != nil {
+		return "", fmt.Errorf("recipient: %w", err)
+	}
+	return address, nil
+}
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/hdwallet"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
+)
+
+// deriveAddresses derives count child addresses of account from start.
+// The indexes must stay below the hardened ones, which also keeps start+count from overflowing.
+func deriveAddresses(account *hdwallet.ExtendedKey, start uint32, count int) ([]model.DerivedAddress, error) {
+	if count < 0 || uint64(start)+uint64(count) > hdwallet.HARDENED_OFFSET {
+		return nil, hdwallet.ErrIndexOutOfRange
+	}
+	addresses := make([]model.DerivedAddress, 0, count)
+	for i := start; i < start+uint32(count); i++ {
+		child, err := account.Child(i)
+		if err != nil {
+			return nil, err
+		}
+		publicKey := child.ECDSAPublicKey()
+		addresses = append(addresses, model.DerivedAddress{
+			Index:             i,
+			PublicKey:         fmt.Sprintf("%064x%064x", publicKey.X, publicKey.Y),
+			BlockchainAddress: model.AddressFromPublicKey(publicKey),
+		})
+	}
+	return addresses, nil
+}
+
+func createHDWallet(c *fiber.Ctx) error {
+	var r model.HDWalletRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if r.WordCount == 0 {
+		r.WordCount = 12
+	}
+	if r.AccountPath == "" {
+		r.AccountPath = hdwallet.DEFAULT_ACCOUNT_PATH
+	}
+	if r.AddressCount == 0 {
+		r.AddressCount = model.HD_DEFAULT_ADDRESS_COUNT
+	}
+
+	var resp model.HDWalletResponse
+	mnemonic := r.Mnemonic
+	if mnemonic == "" {
+		var err error
+		if mnemonic, err = hdwallet.NewMnemonic(r.WordCount); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+		}
+		resp.Mnemonic = mnemonic
+	}
+	seed, err := hdwallet.NewSeed(mnemonic, r.Passphrase)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	master, err := hdwallet.NewMasterKey(seed)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+	}
+	account, err := master.Derive(r.AccountPath)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	resp.AccountPath = r.AccountPath
+	resp.ExtendedPublicKey = account.Neuter().String()
+	if resp.Addresses, err = deriveAddresses(account, 0, r.AddressCount); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+	}
+	return c.JSON(resp)
+}
+
+// deriveHDAddresses derives addresses from an extended public key (watch-only).
+func deriveHDAddresses(c *fiber.Ctx) error {
+	var r model.HDDeriveRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if r.Count == 0 {
+		r.Count = model.HD_DEFAULT_ADDRESS_COUNT
+	}
+	account, err := hdwallet.ParsePublicKey(r.ExtendedPublicKey)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	addresses, err := deriveAddresses(account, r.Start, r.Count)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	return c.JSON(addresses)
+}
+
+// scanHDWallet looks up the balance of each derived address through the amount API.
+// The node has no history API, so an address counts as used when its balance is not zero.
+func scanHDWallet(c *fiber.Ctx) error {
+	var r model.HDScanRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if r.GapLimit == 0 {
+		r.GapLimit = model.HD_DEFAULT_GAP_LIMIT
+	}
+	account, err := hdwallet.ParsePublicKey(r.ExtendedPublicKey)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+
+	resp := model.HDScanResponse{Addresses: []model.DerivedAddress{}}
+	gap := 0
+	for i := uint32(0); gap < r.GapLimit && resp.Scanned < model.HD_MAX_SCAN_ADDRESSES; i++ {
+		derived, err := deriveAddresses(account, i, 1)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+		}
+		a := derived[0]
+		amount, servedBy, err := fetchAmount(a.BlockchainAddress)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+		}
+		c.Set(headerServedBy, servedBy)
+		resp.Scanned++
+		if amount.Amount == 0 {
+			gap++
+			continue
+		}
+		gap = 0
+		a.Amount = &amount.Amount
+		resp.Addresses = append(resp.Addresses, a)
+		resp.TotalAmount += amount.Amount
+	}
+	return c.JSON(resp)
+}
@@ -1,11 +1,60 @@
 package controller
 
 import (
-	"fmt"
+	"encoding/json"
+	"net/http"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
 )
 
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+var healthClient = &http.Client{Timeout: 2 * time.Second}
+
+// health checks every configured node, not only the one the node client would pick.
+// A node of another network doesn't make the wallet healthy, since its addresses are invalid here.
+func health() model.HealthResponse {
+	h := model.HealthResponse{
+		Status:  statusUnavailable,
+		Network: node.network,
+		Nodes:   make([]model.NodeHealth, 0, len(node.nodes)),
+	}
+	for _, n := range node.nodes {
+		nh := model.NodeHealth{URL: n}
+		resp, err := healthClient.Get(n + "/health_check")
+		if err != nil {
+			nodeRequestFailures.WithLabelValues("/health_check").Inc()
+		} else {
+			var nodeHealth struct {
+				Network string `json:"network"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&nodeHealth)
+			resp.Body.Close()
+			nh.Reachable = resp.StatusCode == http.StatusOK
+			nh.Network = nodeHealth.Network
+		}
+		if nh.Reachable && nh.Network == node.network {
+			h.Status = statusOK
+		}
+		h.Nodes = append(h.Nodes, nh)
+	}
+	return h
+}
+
 func healthCheck(c *fiber.Ctx) error {
-	return c.SendString(fmt.Sprintf("Wallet Server! You've requested: %s", c.Path()))
+	return c.JSON(health())
+}
+
+// readiness fails while none of the configured blockchain nodes is reachable.
+func readiness(c *fiber.Ctx) error {
+	h := health()
+	if h.Status != statusOK {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(h)
+	}
+	return c.JSON(h)
 }
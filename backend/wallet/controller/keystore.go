@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/keystore"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
+)
+
+var keys *keystore.Keystore
+
+func keyResponse(kf *keystore.KeyFile) model.KeyResponse {
+	r := model.KeyResponse{
+		ID:                kf.ID,
+		BlockchainAddress: kf.BlockchainAddress,
+		PublicKey:         kf.PublicKey,
+		CreatedAt:         kf.CreatedAt,
+	}
+	if scheme, err := common.SchemeOfAddress(kf.BlockchainAddress); err == nil {
+		r.SignatureScheme = scheme.ID()
+		r.Curve = common.CurveOfScheme(scheme)
+	}
+	if until := keys.UnlockedUntil(kf.ID); !until.IsZero() {
+		r.Unlocked = true
+		r.UnlockedUntil = &until
+	}
+	return r
+}
+
+// keystoreError maps keystore errors to HTTP statuses.
+func keystoreError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, keystore.ErrNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, keystore.ErrWrongPassphrase):
+		status = fiber.StatusUnauthorized
+	case errors.Is(err, keystore.ErrLocked):
+		status = fiber.StatusForbidden
+	case errors.Is(err, keystore.ErrAlreadyExists), errors.Is(err, keystore.ErrKeyChanged):
+		status = fiber.StatusConflict
+	case errors.Is(err, keystore.ErrEmptyPassphrase), errors.Is(err, keystore.ErrInvalidDuration):
+		status = fiber.StatusBadRequest
+	}
+	return c.Status(status).JSON(common.NewResponse(err.Error()))
+}
+
+func createKey(c *fiber.Ctx) error {
+	var r model.KeystoreCreateRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	scheme, err := common.SchemeByName(r.Scheme, r.Curve)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	kf, err := keys.Create(r.Passphrase, scheme)
+	if err != nil {
+		return keystoreError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(keyResponse(kf))
+}
+
+// importKey stores an exported private key. The public key and the address are derived from it.
+func importKey(c *fiber.Ctx) error {
+	var r model.KeyImportRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	scheme, err := common.SchemeByName(r.Scheme, r.Curve)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	w, err := model.ParsePrivateKey(r.PrivateKey, r.Format, scheme)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	kf, err := keys.Import(w, r.Passphrase)
+	if err != nil {
+		return keystoreError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(keyResponse(kf))
+}
+
+// exportKey returns the private key. The passphrase is required even if the key is unlocked.
+func exportKey(c *fiber.Ctx) error {
+	var r model.KeyExportRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	w, err := keys.Open(c.Params("id"), r.Passphrase)
+	if err != nil {
+		return keystoreError(c, err)
+	}
+	privateKey, err := w.ExportPrivateKey(r.Format)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+	}
+	return c.JSON(model.KeyExportResponse{
+		Format:            r.Format,
+		PrivateKey:        privateKey,
+		PublicKey:         w.PublicKeyStr(),
+		BlockchainAddress: w.BlockchainAddress(),
+	})
+}
+
+func listKeys(c *fiber.Ctx) error {
+	kfs, err := keys.List()
+	if err != nil {
+		return keystoreError(c, err)
+	}
+	resp := make([]model.KeyResponse, 0, len(kfs))
+	for _, kf := range kfs {
+		resp = append(resp, keyResponse(kf))
+	}
+	return c.JSON(resp)
+}
+
+func getKey(c *fiber.Ctx) error {
+	kf, err := keys.Find(c.Params("id"))
+	if err != nil {
+		return keystoreError(c, err)
+	}
+	return c.JSON(keyResponse(kf))
+}
+
+func unlockKey(c *fiber.Ctx) error {
+	var r model.KeystoreUnlockRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	id := c.Params("id")
+	if _, err := keys.Unlock(id, r.Passphrase, time.Duration(r.DurationSec)*time.Second); err != nil {
+		return keystoreError(c, err)
+	}
+	kf, err := keys.Find(id)
+	if err != nil {
+		return keystoreError(c, err)
+	}
+	return c.JSON(keyResponse(kf))
+}
+
+func lockKey(c *fiber.Ctx) error {
+	kf, err := keys.Find(c.Params("id"))
+	if err != nil {
+		return keystoreError(c, err)
+	}
+	keys.Lock(kf.ID)
+	return c.JSON(keyResponse(kf))
+}
+
+func changePassphrase(c *fiber.Ctx) error {
+	var r model.KeystorePassphraseRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := keys.ChangePassphrase(c.Params("id"), r.Passphrase, r.NewPassphrase); err != nil {
+		return keystoreError(c, err)
+	}
+	return c.JSON(common.NewResponse("passphrase changed"))
+}
+
+func deleteKey(c *fiber.Ctx) error {
+	var r model.KeystoreDeleteRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := keys.Delete(c.Params("id"), r.Passphrase); err != nil {
+		return keystoreError(c, err)
+	}
+	return c.JSON(common.NewResponse("key deleted"))
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"encoding/hex"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
+)
+
+// signMessage proves the ownership of an address without moving funds.
+// The signature covers common.MessageDigest, which can't be taken for a transaction.
+func signMessage(c *fiber.Ctx) error {
+	var r model.MessageSignRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	w, err := signingWallet(c, r.SenderWallet)
+	if err != nil || w == nil {
+		return err
+	}
+	signature := common.SignMessage(w.Scheme(), w.PrivateKey(), r.Message)
+	encoded := hex.EncodeToString(signature)
+	if r.Encoding == model.SIGNATURE_ENCODING_DER {
+		// DER only exists for ECDSA, whose signatures are R || S
+		if w.Curve() == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(w.Scheme().ID() + " signatures have no DER encoding"))
+		}
+		encoded = common.SignatureFromBytes(signature).DERString()
+	}
+	return c.JSON(model.MessageSignResponse{
+		BlockchainAddress: w.BlockchainAddress(),
+		PublicKey:         w.PublicKeyStr(),
+		Message:           r.Message,
+		Signature:         encoded,
+		SignatureScheme:   w.Scheme().ID(),
+	})
+}
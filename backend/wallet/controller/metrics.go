@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	nodeRequestFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wallet_node_request_failures_total",
+		Help: "Number of failed calls from the wallet server to a blockchain node.",
+	}, []string{"endpoint"})
+)
+
+func metricsMiddleware(c *fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+	status := c.Response().StatusCode()
+	if e, ok := err.(*fiber.Error); ok {
+		status = e.Code
+	}
+	httpRequestDuration.WithLabelValues(c.Method(), c.Route().Path, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	return err
+}
+
+func metrics(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, common.MetricsContentType)
+	return common.WriteMetrics(c)
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/config"
+)
+
+// headerServedBy tells the client which blockchain node served the request.
+const headerServedBy = "X-Blockchain-Node"
+
+var errNoNodeAvailable = errors.New("no blockchain node is available")
+
+// nodeClient sends requests to the configured blockchain nodes.
+// A node that fails is skipped for a cooldown and the next one is tried.
+type nodeClient struct {
+	network       string
+	nodes         []string
+	client        *http.Client
+	retries       int
+	retryInterval time.Duration
+	cooldown      time.Duration
+
+	mux       sync.Mutex
+	downUntil map[string]time.Time
+}
+
+func newNodeClient(c *config.Config) *nodeClient {
+	return &nodeClient{
+		network:       c.Network,
+		nodes:         c.Nodes,
+		client:        &http.Client{Timeout: c.Timeout.Std()},
+		retries:       c.Retries,
+		retryInterval: c.RetryInterval.Std(),
+		cooldown:      c.NodeCooldown.Std(),
+		downUntil:     make(map[string]time.Time),
+	}
+}
+
+// candidates returns the healthy nodes first, then the ones in cooldown
+// so that a request still has a chance when every node was marked down.
+func (nc *nodeClient) candidates() []string {
+	nc.mux.Lock()
+	defer nc.mux.Unlock()
+	now := time.Now()
+	healthy := make([]string, 0, len(nc.nodes))
+	var down []string
+	for _, n := range nc.nodes {
+		if now.Before(nc.downUntil[n]) {
+			down = append(down, n)
+		} else {
+			healthy = append(healthy, n)
+		}
+	}
+	return append(healthy, down...)
+}
+
+func (nc *nodeClient) markDown(node string) {
+	nc.mux.Lock()
+	defer nc.mux.Unlock()
+	nc.downUntil[node] = time.Now().Add(nc.cooldown)
+}
+
+func (nc *nodeClient) markUp(node string) {
+	nc.mux.Lock()
+	defer nc.mux.Unlock()
+	delete(nc.downUntil, node)
+}
+
+// isNodeFailure reports whether the node itself is unhealthy.
+// Other error statuses are answers of a healthy node and are returned as is.
+func isNodeFailure(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
+
+// do sends a request to the first node that answers and returns which node it was.
+// path is relative to the node URL, e.g. "/transactions".
+//
+// A GET is retried and fails over to the next node. A POST may already have been processed by
+// a node that failed to answer, e.g. its transaction is in the pool, so it is only sent again when
+// the connection failed before the request was written. Otherwise the failure is returned as is.
+func (nc *nodeClient) do(method, path string, body []byte) (*http.Response, string, error) {
+	idempotent := method == http.MethodGet
+	for _, node := range nc.candidates() {
+		for attempt := 0; attempt <= nc.retries; attempt++ {
+			if attempt > 0 {
+				time.Sleep(nc.retryInterval)
+			}
+			var reader io.Reader
+			if body != nil {
+				reader = bytes.NewReader(body)
+			}
+			req, err := http.NewRequest(method, node+path, reader)
+			if err != nil {
+				return nil, "", err
+			}
+			if body != nil {
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			}
+			var sent atomic.Bool
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+				WroteHeaders: func() { sent.Store(true) },
+			}))
+			resp, err := nc.client.Do(req)
+			if err == nil && !isNodeFailure(resp.StatusCode) {
+				nc.markUp(node)
+				return resp, node, nil
+			}
+			nodeRequestFailures.WithLabelValues(path).Inc()
+			if err == nil {
+				log.Printf("ERROR: node=%s path=%s attempt=%d status %d", node, path, attempt+1, resp.StatusCode)
+				if !idempotent {
+					nc.markDown(node)
+					return resp, node, nil
+				}
+				resp.Body.Close()
+				continue
+			}
+			log.Printf("ERROR: node=%s path=%s attempt=%d %v", node, path, attempt+1, err)
+			if !idempotent && sent.Load() {
+				nc.markDown(node)
+				return nil, "", fmt.Errorf("%s failed after the request was sent, so it may have been processed: %w", node, err)
+			}
+		}
+		nc.markDown(node)
+	}
+	return nil, "", errNoNodeAvailable
+}
+
+func (nc *nodeClient) get(path string) (*http.Response, string, error) {
+	return nc.do(http.MethodGet, path, nil)
+}
+
+func (nc *nodeClient) post(path string, body []byte) (*http.Response, string, error) {
+	return nc.do(http.MethodPost, path, body)
+}
+
+// nodeError is the error of a GET that the node answered with a status other than 2xx.
+// It carries the message of the node, or the body when it is not a common.Response (e.g. a validation error).
+func nodeError(path string, resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	var r common.Response
+	if json.Unmarshal(b, &r) == nil && r.Message != "" {
+		return fmt.Errorf("GET %s: status %d: %s", path, resp.StatusCode, r.Message)
+	}
+	if msg := string(bytes.TrimSpace(b)); msg != "" {
+		return fmt.Errorf("GET %s: status %d: %s", path, resp.StatusCode, msg)
+	}
+	return fmt.Errorf("GET %s: status %d", path, resp.StatusCode)
+}
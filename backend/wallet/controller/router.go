@@ -2,17 +2,62 @@ package controller
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/addressbook"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/config"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/keystore"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/watchonly"
 )
 
-func InitRouter() *fiber.App {
+var node *nodeClient
+
+func InitRouter(c *config.Config, ks *keystore.Keystore, ws *watchonly.Store, ab *addressbook.Store) *fiber.App {
+	node = newNodeClient(c)
+	keys = ks
+	watched = ws
+	contacts = ab
+
 	app := fiber.New()
+	app.Use(metricsMiddleware)
+	app.Get("/metrics", metrics)
 	v1 := app.Group("/v1")
 	// v1/health_check
 	v1.Get("/health_check", healthCheck)
+	v1.Get("/ready", readiness)
 	// v1/wallet
 	v1.Post("/wallet", createWallet)
 	v1.Get("/wallet/amount", getAmount)
+	v1.Post("/transactions/prepare", prepareTransaction)
 	v1.Post("/transactions", createTransaction)
+	v1.Post("/transactions/batch/prepare", prepareBatchTransaction)
+	v1.Post("/transactions/batch", createBatchTransaction)
+	v1.Post("/messages/sign", signMessage)
+	// v1/hd: hierarchical deterministic wallets
+	v1.Post("/hd/wallet", createHDWallet)
+	v1.Post("/hd/addresses", deriveHDAddresses)
+	v1.Post("/hd/scan", scanHDWallet)
+	// v1/keystore: :id is the key ID or its blockchain address.
+	v1.Post("/keystore", createKey)
+	v1.Get("/keystore", listKeys)
+	v1.Post("/keystore/import", importKey)
+	v1.Get("/keystore/:id", getKey)
+	v1.Post("/keystore/:id/unlock", unlockKey)
+	v1.Post("/keystore/:id/export", exportKey)
+	v1.Post("/keystore/:id/lock", lockKey)
+	v1.Put("/keystore/:id/passphrase", changePassphrase)
+	v1.Delete("/keystore/:id", deleteKey)
+	// v1/watch: watch-only wallets. :address is the blockchain address.
+	v1.Post("/watch", addWatch)
+	v1.Get("/watch", listWatch)
+	v1.Get("/watch/balances", watchBalances)
+	v1.Get("/watch/history", watchHistory)
+	v1.Get("/watch/:address", getWatch)
+	v1.Delete("/watch/:address", deleteWatch)
+	// v1/contacts: address book. :name is the contact name.
+	v1.Post("/contacts", createContact)
+	v1.Get("/contacts", listContacts)
+	v1.Get("/contacts/:name", getContact)
+	v1.Put("/contacts/:name", updateContact)
+	v1.Delete("/contacts/:name", deleteContact)
 
 	return app
 }
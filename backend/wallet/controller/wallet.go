@@ -1,25 +1,70 @@
 package controller
 
 import (
-	"bytes"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/keystore"
 	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
 )
 
-const (
-	gateWayURL = "http://localhost:8001/v1"
-)
-
+// createWallet returns a new key of the scheme in ?scheme=, ECDSA P-256 by default.
 func createWallet(c *fiber.Ctx) error {
-	myWallet := model.NewWallet()
+	scheme, err := common.SchemeByName(c.Query("scheme"), "")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	myWallet, err := model.NewSchemeWallet(scheme)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+	}
 	return c.JSON(myWallet)
 }
 
+// prepareTransaction returns the exact message a client has to sign locally.
+func prepareTransaction(c *fiber.Ctx) error {
+	var t model.PrepareTransactionRequest
+	if err := c.BodyParser(&t); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := t.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	publicKey, err := common.ParsePublicKeyForAddress(t.SenderPublicKey, t.SenderBlockchainAddress)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	if model.AddressFromPublicKey(publicKey) != t.SenderBlockchainAddress {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse("sender_public_key does not match sender_blockchain_address"))
+	}
+	recipient := ""
+	if t.Type == "" {
+		if recipient, err = resolveRecipient(t.RecipientBlockchainAddress, t.RecipientContact); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+		}
+	}
+	nonce, err := fetchNonce(t.SenderBlockchainAddress)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+	}
+
+	m := transactionMessage(t.SenderBlockchainAddress, recipient, t.Type, t.Value, nonce)
+	return c.JSON(model.PrepareTransactionResponse{
+		ID:          m.ID(),
+		Transaction: m,
+		Message:     string(m.Bytes()),
+		Digest:      hex.EncodeToString(m.Digest()),
+	})
+}
+
+// createTransaction forwards a transaction signed by the client to the blockchain node.
 func createTransaction(c *fiber.Ctx) error {
 	var t model.TransactionRequest
 	if err := c.BodyParser(&t); err != nil {
@@ -28,12 +73,22 @@ func createTransaction(c *fiber.Ctx) error {
 	if err := t.Validate(); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(err)
 	}
+	if t.Type == "" {
+		recipient, err := resolveRecipient(t.RecipientBlockchainAddress, t.RecipientContact)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+		}
+		t.RecipientBlockchainAddress = recipient
+	}
+	if t.SenderWallet != "" {
+		return createTransactionFromKeystore(c, &t)
+	}
 
-	publicKey := common.PublicKeyFromString(t.SenderPublicKey)
-	privateKey := common.PrivateKeyFromString(t.SenderPrivateKey, publicKey)
-	transaction := model.NewTransaction(privateKey, publicKey, t.SenderBlockchainAddress, t.RecipientBlockchainAddress, t.Value)
-	signature := transaction.GenerateSignature()
-	signatureStr := signature.String()
+	digest := transactionMessage(t.SenderBlockchainAddress, t.RecipientBlockchainAddress, t.Type, t.Value, t.Nonce).Digest()
+	scheme, err := verifyClientSignature(c, t.SignatureScheme, t.SenderBlockchainAddress, t.SenderPublicKey, t.Signature, digest)
+	if err != nil || scheme == nil {
+		return err
+	}
 
 	// blockchain serverに投げる用
 	bt := &model.BlockchainTransactionRequest{
@@ -41,34 +96,155 @@ func createTransaction(c *fiber.Ctx) error {
 		RecipientBlockchainAddress: t.RecipientBlockchainAddress,
 		SenderPublicKey:            t.SenderPublicKey,
 		Value:                      t.Value,
-		Signature:                  signatureStr,
+		Signature:                  t.Signature,
+		SignatureScheme:            scheme.ID(),
+		Type:                       t.Type,
+		Nonce:                      t.Nonce,
+	}
+	return sendTransaction(c, bt)
+}
+
+// transactionMessage is what a transaction request signs. Stake and unstake have no recipient.
+func transactionMessage(sender, recipient, typ string, value float64, nonce uint64) *common.TransactionMessage {
+	if typ != "" {
+		return common.NewStakeTransactionMessage(sender, typ, value, nonce)
+	}
+	return common.NewTransactionMessage(sender, recipient, value, nonce)
+}
+
+// verifyClientSignature checks a transaction signed by the client before it goes to the node.
+// When it isn't valid, it writes the error response and returns a nil scheme.
+func verifyClientSignature(c *fiber.Ctx, schemeID, sender, publicKey, signature string, digest []byte) (common.SignatureScheme, error) {
+	scheme, err := common.SchemeOfTransaction(schemeID, sender)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	key, err := scheme.ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	if scheme.Address(key) != sender {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(common.NewResponse("sender_public_key does not match sender_blockchain_address"))
 	}
+	sig, err := scheme.ParseSignature(signature)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	if !scheme.Verify(key, digest, sig) {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(common.NewResponse("invalid signature"))
+	}
+	return scheme, nil
+}
+
+// createTransactionFromKeystore signs with a key unlocked in the keystore.
+func createTransactionFromKeystore(c *fiber.Ctx, t *model.TransactionRequest) error {
+	w, err := signingWallet(c, t.SenderWallet)
+	if err != nil || w == nil {
+		return err
+	}
+	nonce, err := fetchNonce(w.BlockchainAddress())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+	}
+	transaction := model.NewTransaction(w.PrivateKey(), w.PublicKey(), w.BlockchainAddress(), t.RecipientBlockchainAddress, t.Value, nonce)
+	if t.Type != "" {
+		transaction = model.NewStakeTransaction(w.PrivateKey(), w.PublicKey(), w.BlockchainAddress(), t.Type, t.Value, nonce)
+	}
+	bt := &model.BlockchainTransactionRequest{
+		SenderBlockchainAddress:    w.BlockchainAddress(),
+		RecipientBlockchainAddress: t.RecipientBlockchainAddress,
+		SenderPublicKey:            w.PublicKeyStr(),
+		Value:                      t.Value,
+		Signature:                  transaction.GenerateSignature(),
+		SignatureScheme:            w.Scheme().ID(),
+		Type:                       t.Type,
+		Nonce:                      nonce,
+	}
+	return sendTransaction(c, bt)
+}
+
+// signingWallet returns the unlocked wallet of the keystore. When it can't, it writes
+// the error response and returns a nil wallet.
+func signingWallet(c *fiber.Ctx, senderWallet string) (*model.Wallet, error) {
+	w, err := keys.Wallet(senderWallet)
+	if errors.Is(err, keystore.ErrNotFound) {
+		if _, werr := watched.Find(senderWallet); werr == nil {
+			return nil, c.Status(fiber.StatusForbidden).JSON(common.NewResponse(
+				fmt.Sprintf("%s is a watch-only wallet and has no private key to sign with", senderWallet)))
+		}
+	}
+	if err != nil {
+		return nil, keystoreError(c, err)
+	}
+	return w, nil
+}
+
+func sendTransaction(c *fiber.Ctx, bt *model.BlockchainTransactionRequest) error {
 	btByte, _ := json.Marshal(bt)
-	buf := bytes.NewBuffer(btByte)
 
-	resp, err := http.Post(gateWayURL+"/transactions", "application/json", buf)
+	resp, servedBy, err := node.post("/transactions", btByte)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(err)
+		return c.Status(http.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
 	}
+	defer resp.Body.Close()
+	c.Set(headerServedBy, servedBy)
 
-	if resp.StatusCode == fiber.StatusCreated {
-		return c.SendStatus(fiber.StatusCreated)
+	// pass on the transaction ID, or the reason why the node rejected the transaction.
+	if resp.StatusCode == fiber.StatusCreated || resp.StatusCode == fiber.StatusBadRequest {
+		body, err := io.ReadAll(resp.Body)
+		if err == nil {
+			c.Set(fiber.HeaderContentType, resp.Header.Get(fiber.HeaderContentType))
+			return c.Status(resp.StatusCode).Send(body)
+		}
 	}
 	return c.SendStatus(fiber.StatusInternalServerError)
 }
 
 func getAmount(c *fiber.Ctx) error {
 	bcAddress := c.Query("blockchain_address")
-	whereAddress := fmt.Sprintf("?blockchain_address=%s", bcAddress)
-	bcResp, err := http.Get(gateWayURL + fmt.Sprintf("/amount%s", whereAddress))
+	resp, servedBy, err := fetchAmount(bcAddress)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(err)
+		return c.Status(http.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+	}
+	c.Set(headerServedBy, servedBy)
+	return c.JSON(resp)
+}
+
+// fetchNonce asks a blockchain node for the nonce of the next transaction of bcAddress.
+func fetchNonce(bcAddress string) (uint64, error) {
+	bcResp, _, err := node.get(fmt.Sprintf("/nonce?blockchain_address=%s", url.QueryEscape(bcAddress)))
+	if err != nil {
+		return 0, err
+	}
+	defer bcResp.Body.Close()
+	if bcResp.StatusCode < 200 || bcResp.StatusCode > 299 {
+		return 0, nodeError("/nonce", bcResp)
+	}
+	var resp model.NonceResponse
+	if err := json.NewDecoder(bcResp.Body).Decode(&resp); err != nil {
+		nodeRequestFailures.WithLabelValues("/nonce").Inc()
+		return 0, err
+	}
+	return resp.Nonce, nil
+}
+
+// fetchAmount asks a blockchain node for the balance of bcAddress.
+func fetchAmount(bcAddress string) (*common.AmountResponse, string, error) {
+	whereAddress := fmt.Sprintf("?blockchain_address=%s", url.QueryEscape(bcAddress))
+	bcResp, servedBy, err := node.get(fmt.Sprintf("/amount%s", whereAddress))
+	if err != nil {
+		return nil, "", err
+	}
+	defer bcResp.Body.Close()
+	if bcResp.StatusCode < 200 || bcResp.StatusCode > 299 {
+		return nil, servedBy, nodeError("/amount", bcResp)
 	}
 
 	decorder := json.NewDecoder(bcResp.Body)
-	var resp model.AmountResponse
+	var resp common.AmountResponse
 	if err := decorder.Decode(&resp); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+		nodeRequestFailures.WithLabelValues("/amount").Inc()
+		return nil, servedBy, err
 	}
-	return c.JSON(resp)
+	return &resp, servedBy, nil
 }
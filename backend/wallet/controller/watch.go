@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/watchonly"
+)
+
+var watched *watchonly.Store
+
+// watchError maps watch-only store errors to HTTP statuses.
+func watchError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, watchonly.ErrNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, watchonly.ErrAlreadyWatched):
+		status = fiber.StatusConflict
+	case errors.Is(err, watchonly.ErrKeyMismatch), errors.Is(err, watchonly.ErrNoAddress),
+		errors.Is(err, model.ErrInvalidAddress), errors.Is(err, model.ErrInvalidPublicKey):
+		status = fiber.StatusBadRequest
+	}
+	return c.Status(status).JSON(common.NewResponse(err.Error()))
+}
+
+func addWatch(c *fiber.Ctx) error {
+	var r model.WatchRequest
+	if err := c.BodyParser(&r); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	if err := r.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(err)
+	}
+	scheme, err := common.SchemeByName(r.Scheme, r.Curve)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(err.Error()))
+	}
+	w, err := watched.Add(r.Label, r.BlockchainAddress, r.PublicKey, scheme)
+	if err != nil {
+		return watchError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(w)
+}
+
+func listWatch(c *fiber.Ctx) error {
+	return c.JSON(watched.List())
+}
+
+func getWatch(c *fiber.Ctx) error {
+	w, err := watched.Find(c.Params("address"))
+	if err != nil {
+		return watchError(c, err)
+	}
+	return c.JSON(w)
+}
+
+func deleteWatch(c *fiber.Ctx) error {
+	if err := watched.Delete(c.Params("address")); err != nil {
+		return watchError(c, err)
+	}
+	return c.JSON(common.NewResponse("watch-only wallet deleted"))
+}
+
+// watchBalances returns the balance of every watch-only wallet and their total.
+func watchBalances(c *fiber.Ctx) error {
+	wallets := watched.List()
+	resp := model.WatchBalancesResponse{Wallets: make([]model.WatchBalance, 0, len(wallets))}
+	for _, w := range wallets {
+		amount, servedBy, err := fetchAmount(w.BlockchainAddress)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+		}
+		c.Set(headerServedBy, servedBy)
+		resp.Wallets = append(resp.Wallets, model.WatchBalance{
+			Label:             w.Label,
+			BlockchainAddress: w.BlockchainAddress,
+			Amount:            amount.Amount,
+		})
+		resp.TotalAmount += amount.Amount
+	}
+	return c.JSON(resp)
+}
+
+// watchHistory returns the transactions in the chain that touch any watch-only wallet, oldest first.
+func watchHistory(c *fiber.Ctx) error {
+	labels := make(map[string]string)
+	for _, w := range watched.List() {
+		labels[w.BlockchainAddress] = w.Label
+	}
+	chain, servedBy, err := fetchChain()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(common.NewResponse(err.Error()))
+	}
+	c.Set(headerServedBy, servedBy)
+
+	resp := model.WatchHistoryResponse{History: []model.WatchHistoryEntry{}}
+	for height, b := range chain.Chain {
+		for _, t := range b.Transactions {
+			senderLabel, fromWatched := labels[t.SenderBlockchainAddress]
+			recipientLabel := labels[t.RecipientBlockchainAddress]
+			// value paid to the watched set and to the outside
+			inside, outside := 0.0, 0.0
+			if len(t.Outputs) > 0 {
+				for _, o := range t.Outputs {
+					if _, ok := labels[o.RecipientBlockchainAddress]; ok {
+						inside += o.Value
+					} else {
+						outside += o.Value
+					}
+				}
+			} else if _, ok := labels[t.RecipientBlockchainAddress]; ok {
+				inside = t.Value
+			} else {
+				outside = t.Value
+			}
+			var direction string
+			switch {
+			case fromWatched && outside == 0:
+				direction = model.DIRECTION_INTERNAL
+			case fromWatched:
+				direction = model.DIRECTION_OUT
+				resp.Sent += outside
+			case inside > 0:
+				direction = model.DIRECTION_IN
+				resp.Received += inside
+			default:
+				continue
+			}
+			resp.History = append(resp.History, model.WatchHistoryEntry{
+				BlockHeight:                height,
+				Timestamp:                  b.Timestamp,
+				SenderBlockchainAddress:    t.SenderBlockchainAddress,
+				SenderLabel:                senderLabel,
+				RecipientBlockchainAddress: t.RecipientBlockchainAddress,
+				RecipientLabel:             recipientLabel,
+				Value:                      t.Value,
+				Direction:                  direction,
+				Outputs:                    t.Outputs,
+			})
+		}
+	}
+	return c.JSON(resp)
+}
+
+func fetchChain() (*common.ChainResponse, string, error) {
+	bcResp, servedBy, err := node.get("/chain")
+	if err != nil {
+		return nil, "", err
+	}
+	defer bcResp.Body.Close()
+	if bcResp.StatusCode != fiber.StatusOK {
+		return nil, servedBy, nodeError("/chain", bcResp)
+	}
+	var chain common.ChainResponse
+	if err := json.NewDecoder(bcResp.Body).Decode(&chain); err != nil {
+		nodeRequestFailures.WithLabelValues("/chain").Inc()
+		return nil, servedBy, err
+	}
+	return &chain, servedBy, nil
+}
@@ -0,0 +1,301 @@
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	HARDENED_OFFSET = 0x80000000
+
+	// BIP44 layout: m / purpose' / coin_type' / account' / change / address_index
+	DEFAULT_ACCOUNT_PATH = "m/44'/0'/0'/0"
+
+	// SLIP-0010 master key HMAC key for P-256.
+	p256SeedKey = "Nist256p1 seed"
+)
+
+var (
+	// serialization version bytes. The xprv / xpub of BIP32 are secp256k1 keys, so P-256 keys
+	// have their own versions and are written as pprv... / ppub..., and a Bitcoin key is never
+	// read as a key of this curve.
+	versionPrivate = []byte{0x03, 0xe2, 0x59, 0x46}
+	versionPublic  = []byte{0x03, 0xe2, 0x5d, 0x80}
+
+	ErrHardenedFromPublic = errors.New("cannot derive a hardened child from a public key")
+	ErrInvalidKey         = errors.New("invalid extended key")
+	ErrNotPublicKey       = errors.New("an extended public key (ppub...) is required, not a private key")
+	ErrIndexOutOfRange    = fmt.Errorf("child indexes must be below %d, the hardened indexes", HARDENED_OFFSET)
+)
+
+var curve = elliptic.P256()
+
+// ExtendedKey is a private or public key with the chain code needed to derive its children.
+type ExtendedKey struct {
+	key         []byte // 32 bytes private key or 33 bytes compressed public key
+	chainCode   []byte
+	depth       uint8
+	fingerprint []byte // parent fingerprint
+	childNumber uint32
+	private     bool
+}
+
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("seed must be 16-64 bytes, got %d", len(seed))
+	}
+	I := hmacSHA512([]byte(p256SeedKey), seed)
+	// retry with I as the data while IL is not a valid private key.
+	for !isValidPrivateKey(I[:32]) {
+		I = hmacSHA512([]byte(p256SeedKey), I)
+	}
+	return &ExtendedKey{
+		key:         I[:32],
+		chainCode:   I[32:],
+		fingerprint: []byte{0, 0, 0, 0},
+		private:     true,
+	}, nil
+}
+
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.private
+}
+
+func (k *ExtendedKey) Depth() uint8 {
+	return k.depth
+}
+
+func (k *ExtendedKey) ChildNumber() uint32 {
+	return k.childNumber
+}
+
+// Child derives the i-th child. i >= HARDENED_OFFSET is a hardened child.
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	hardened := i >= HARDENED_OFFSET
+	if hardened && !k.private {
+		return nil, ErrHardenedFromPublic
+	}
+	publicKey := k.publicKeyBytes()
+
+	var data []byte
+	if hardened {
+		data = append([]byte{0x00}, k.key...)
+	} else {
+		data = append([]byte{}, publicKey...)
+	}
+	data = binary.BigEndian.AppendUint32(data, i)
+
+	n := curve.Params().N
+	for {
+		I := hmacSHA512(k.chainCode, data)
+		IL, IR := I[:32], I[32:]
+		child := &ExtendedKey{
+			chainCode:   IR,
+			depth:       k.depth + 1,
+			fingerprint: hash160(publicKey)[:4],
+			childNumber: i,
+			private:     k.private,
+		}
+		if new(big.Int).SetBytes(IL).Cmp(n) < 0 {
+			if k.private {
+				ki := new(big.Int).SetBytes(IL)
+				ki.Add(ki, new(big.Int).SetBytes(k.key))
+				ki.Mod(ki, n)
+				if ki.Sign() != 0 {
+					child.key = ki.FillBytes(make([]byte, 32))
+					return child, nil
+				}
+			} else {
+				x, y := curve.ScalarBaseMult(IL)
+				px, py := elliptic.UnmarshalCompressed(curve, k.key)
+				x, y = curve.Add(x, y, px, py)
+				if x.Sign() != 0 || y.Sign() != 0 {
+					child.key = elliptic.MarshalCompressed(curve, x, y)
+					return child, nil
+				}
+			}
+		}
+		// SLIP-0010: the key is invalid, derive again from IR.
+		data = append([]byte{0x01}, IR...)
+		data = binary.BigEndian.AppendUint32(data, i)
+	}
+}
+
+// Derive follows a path such as "m/44'/0'/0'/0/1" from this key.
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := k
+	for _, i := range indexes {
+		if key, err = key.Child(i); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Neuter returns the extended public key, which can derive only non-hardened children.
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.private {
+		return k
+	}
+	return &ExtendedKey{
+		key:         k.publicKeyBytes(),
+		chainCode:   k.chainCode,
+		depth:       k.depth,
+		fingerprint: k.fingerprint,
+		childNumber: k.childNumber,
+	}
+}
+
+func (k *ExtendedKey) ECDSAPrivateKey() (*ecdsa.PrivateKey, error) {
+	if !k.private {
+		return nil, errors.New("not a private key")
+	}
+	d := new(big.Int).SetBytes(k.key)
+	x, y := curve.ScalarBaseMult(k.key)
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y},
+		D:         d,
+	}, nil
+}
+
+func (k *ExtendedKey) ECDSAPublicKey() *ecdsa.PublicKey {
+	x, y := elliptic.UnmarshalCompressed(curve, k.publicKeyBytes())
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+}
+
+func (k *ExtendedKey) publicKeyBytes() []byte {
+	if !k.private {
+		return k.key
+	}
+	x, y := curve.ScalarBaseMult(k.key)
+	return elliptic.MarshalCompressed(curve, x, y)
+}
+
+// String serializes the key in the BIP32 format (base58 with a 4 byte checksum).
+func (k *ExtendedKey) String() string {
+	b := make([]byte, 0, 82)
+	if k.private {
+		b = append(b, versionPrivate...)
+	} else {
+		b = append(b, versionPublic...)
+	}
+	b = append(b, k.depth)
+	b = append(b, k.fingerprint...)
+	b = binary.BigEndian.AppendUint32(b, k.childNumber)
+	b = append(b, k.chainCode...)
+	if k.private {
+		b = append(b, 0x00)
+	}
+	b = append(b, k.key...)
+	return base58.Encode(append(b, doubleSHA256(b)[:4]...))
+}
+
+// ParsePublicKey parses an extended public key and rejects a private one,
+// so that a private key is never sent where only a public key is needed.
+func ParsePublicKey(s string) (*ExtendedKey, error) {
+	k, err := ParseExtendedKey(s)
+	if err != nil {
+		return nil, err
+	}
+	if k.private {
+		return nil, ErrNotPublicKey
+	}
+	return k, nil
+}
+
+func ParseExtendedKey(s string) (*ExtendedKey, error) {
+	b := base58.Decode(s)
+	if len(b) != 82 {
+		return nil, ErrInvalidKey
+	}
+	payload, checksum := b[:78], b[78:]
+	if !bytes.Equal(doubleSHA256(payload)[:4], checksum) {
+		return nil, ErrInvalidKey
+	}
+	k := &ExtendedKey{
+		depth:       payload[4],
+		fingerprint: payload[5:9],
+		childNumber: binary.BigEndian.Uint32(payload[9:13]),
+		chainCode:   payload[13:45],
+	}
+	keyData := payload[45:78]
+	switch {
+	case bytes.Equal(payload[:4], versionPrivate):
+		if keyData[0] != 0x00 || !isValidPrivateKey(keyData[1:]) {
+			return nil, ErrInvalidKey
+		}
+		k.key = keyData[1:]
+		k.private = true
+	case bytes.Equal(payload[:4], versionPublic):
+		if x, _ := elliptic.UnmarshalCompressed(curve, keyData); x == nil {
+			return nil, ErrInvalidKey
+		}
+		k.key = keyData
+	default:
+		return nil, ErrInvalidKey
+	}
+	return k, nil
+}
+
+// ParsePath parses "m/44'/0'/0'/0/1". Both ' and h mark a hardened index.
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, fmt.Errorf("path must start with m: %q", path)
+	}
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		hardened := strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h")
+		p = strings.TrimRight(p, "'h")
+		i, err := strconv.ParseUint(p, 10, 32)
+		if err != nil || i >= HARDENED_OFFSET {
+			return nil, fmt.Errorf("invalid path element %q", p)
+		}
+		if hardened {
+			i += HARDENED_OFFSET
+		}
+		indexes = append(indexes, uint32(i))
+	}
+	return indexes, nil
+}
+
+func isValidPrivateKey(b []byte) bool {
+	d := new(big.Int).SetBytes(b)
+	return d.Sign() > 0 && d.Cmp(curve.Params().N) < 0
+}
+
+func hmacSHA512(key, data []byte) []byte {
+	m := hmac.New(sha512.New, key)
+	m.Write(data)
+	return m.Sum(nil)
+}
+
+func hash160(b []byte) []byte {
+	h := sha256.Sum256(b)
+	r := ripemd160.New()
+	r.Write(h[:])
+	return r.Sum(nil)
+}
+
+func doubleSHA256(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:]
+}
@@ -0,0 +1,138 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// SLIP-0010 test vectors for nist256p1.
+// https://github.com/satoshilabs/slips/blob/master/slip-0010.md#test-vectors
+func TestSLIP0010Vectors(t *testing.T) {
+	tests := []struct {
+		seed       string
+		path       string
+		chainCode  string
+		privateKey string
+		publicKey  string
+	}{
+		// test vector 1
+		{
+			seed:       "000102030405060708090a0b0c0d0e0f",
+			path:       "m",
+			chainCode:  "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea",
+			privateKey: "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2",
+			publicKey:  "0266874dc6ade47b3ecd096745ca09bcd29638dd52c2c12117b11ed3e458cfa9e8",
+		},
+		{
+			seed:       "000102030405060708090a0b0c0d0e0f",
+			path:       "m/0'",
+			chainCode:  "3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11",
+			privateKey: "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c",
+			publicKey:  "0384610f5ecffe8fda089363a41f56a5c7ffc1d81b59a612d0d649b2d22355590c",
+		},
+		// derivation retry: IL of m/28578'/33941 is not a valid key
+		{
+			seed:       "000102030405060708090a0b0c0d0e0f",
+			path:       "m/28578'",
+			chainCode:  "e94c8ebe30c2250a14713212f6449b20f3329105ea15b652ca5bdfc68f6c65c2",
+			privateKey: "06f0db126f023755d0b8d86d4591718a5210dd8d024e3e14b6159d63f53aa669",
+		},
+		{
+			seed:       "000102030405060708090a0b0c0d0e0f",
+			path:       "m/28578'/33941",
+			chainCode:  "9e87fe95031f14736774cd82f25fd885065cb7c358c1edf813c72af535e83071",
+			privateKey: "092154eed4af83e078ff9b84322015aefe5769e31270f62c3f66c33888335f3a",
+		},
+		// seed retry: IL of the master key is not a valid key
+		{
+			seed:       "a7305bc8df8d0951f0cb224c0e95d7707cbdf2c6ce7e8d481fec69c7ff5e9446",
+			path:       "m",
+			chainCode:  "7762f9729fed06121fd13f326884c82f59aa95c57ac492ce8c9654e60efd130c",
+			privateKey: "3b8c18469a4634517d6d0b65448f8e6c62091b45540a1743c5846be55d47d88f",
+		},
+	}
+	for _, tt := range tests {
+		seed, _ := hex.DecodeString(tt.seed)
+		master, err := NewMasterKey(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, err := master.Derive(tt.path)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.seed, tt.path, err)
+		}
+		if got := hex.EncodeToString(k.chainCode); got != tt.chainCode {
+			t.Errorf("%s %s: chain code = %s, want %s", tt.seed, tt.path, got, tt.chainCode)
+		}
+		if got := hex.EncodeToString(k.key); got != tt.privateKey {
+			t.Errorf("%s %s: private key = %s, want %s", tt.seed, tt.path, got, tt.privateKey)
+		}
+		if got := hex.EncodeToString(k.publicKeyBytes()); tt.publicKey != "" && got != tt.publicKey {
+			t.Errorf("%s %s: public key = %s, want %s", tt.seed, tt.path, got, tt.publicKey)
+		}
+	}
+}
+
+// A non-hardened child of the public key is the public key of the child of the private key.
+func TestPublicDerivation(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, _ := NewMasterKey(seed)
+	account, err := master.Derive(DEFAULT_ACCOUNT_PATH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []uint32{0, 1, 28578} {
+		private, err := account.Child(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public, err := account.Neuter().Child(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if private.Neuter().String() != public.String() {
+			t.Errorf("child %d: %s, want %s", i, public, private.Neuter())
+		}
+	}
+	if _, err := account.Neuter().Child(HARDENED_OFFSET); !errors.Is(err, ErrHardenedFromPublic) {
+		t.Errorf("hardened child of a public key: err = %v, want %v", err, ErrHardenedFromPublic)
+	}
+}
+
+func TestExtendedKeyString(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, _ := NewMasterKey(seed)
+	account, _ := master.Derive(DEFAULT_ACCOUNT_PATH)
+
+	for _, k := range []*ExtendedKey{account, account.Neuter()} {
+		s := k.String()
+		prefix := "ppub"
+		if k.IsPrivate() {
+			prefix = "pprv"
+		}
+		if !strings.HasPrefix(s, prefix) {
+			t.Errorf("%s does not start with %s", s, prefix)
+		}
+		parsed, err := ParseExtendedKey(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.String() != s {
+			t.Errorf("round trip: %s, want %s", parsed, s)
+		}
+	}
+
+	if _, err := ParsePublicKey(account.String()); !errors.Is(err, ErrNotPublicKey) {
+		t.Errorf("ParsePublicKey(pprv): err = %v, want %v", err, ErrNotPublicKey)
+	}
+	if _, err := ParsePublicKey(account.Neuter().String()); err != nil {
+		t.Errorf("ParsePublicKey(ppub): %v", err)
+	}
+	// BIP32 test vector 1 m: a secp256k1 key, not a key of this curve.
+	xpub := "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"
+	if _, err := ParseExtendedKey(xpub); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("ParseExtendedKey(xpub): err = %v, want %v", err, ErrInvalidKey)
+	}
+}
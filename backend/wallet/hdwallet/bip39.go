@@ -0,0 +1,126 @@
+// Package hdwallet implements hierarchical deterministic wallets.
+// Mnemonic phrases follow BIP39 and key derivation follows SLIP-0010,
+// which is BIP32 generalized to the NIST P-256 curve used by this blockchain.
+// HD wallets only derive P-256 keys; secp256k1 keys live in the keystore.
+// https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki
+// https://github.com/satoshilabs/slips/blob/master/slip-0010.md
+package hdwallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+//go:embed english.txt
+var englishWordList string
+
+var (
+	wordList  = strings.Fields(englishWordList)
+	wordIndex = func() map[string]int {
+		m := make(map[string]int, len(wordList))
+		for i, w := range wordList {
+			m[w] = i
+		}
+		return m
+	}()
+)
+
+var (
+	ErrInvalidWordCount   = errors.New("mnemonic must have 12, 15, 18, 21 or 24 words")
+	ErrInvalidChecksum    = errors.New("mnemonic checksum is invalid")
+	ErrNonASCIIPassphrase = errors.New("passphrase must be ASCII")
+)
+
+// NewMnemonic generates a mnemonic phrase of wordCount words.
+func NewMnemonic(wordCount int) (string, error) {
+	// 11 bits per word, 1 checksum bit per 32 bits of entropy.
+	if wordCount%3 != 0 || wordCount < 12 || wordCount > 24 {
+		return "", ErrInvalidWordCount
+	}
+	entropy := make([]byte, wordCount*11*32/33/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+	return MnemonicFromEntropy(entropy)
+}
+
+func MnemonicFromEntropy(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if bits%32 != 0 || bits < 128 || bits > 256 {
+		return "", fmt.Errorf("entropy must be 128-256 bits and a multiple of 32, got %d", bits)
+	}
+	checksumBits := bits / 32
+	h := sha256.Sum256(entropy)
+
+	// entropy || checksum as one big number, read 11 bits at a time.
+	b := new(big.Int).SetBytes(entropy)
+	b.Lsh(b, uint(checksumBits))
+	b.Or(b, big.NewInt(int64(h[0]>>(8-checksumBits))))
+
+	wordCount := (bits + checksumBits) / 11
+	words := make([]string, wordCount)
+	mask := big.NewInt(2047)
+	for i := wordCount - 1; i >= 0; i-- {
+		idx := new(big.Int).And(b, mask).Int64()
+		words[i] = wordList[idx]
+		b.Rsh(b, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// EntropyFromMnemonic checks the words and the checksum of a mnemonic.
+func EntropyFromMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return nil, ErrInvalidWordCount
+	}
+	b := new(big.Int)
+	for _, w := range words {
+		idx, ok := wordIndex[strings.ToLower(w)]
+		if !ok {
+			return nil, fmt.Errorf("unknown word %q", w)
+		}
+		b.Lsh(b, 11)
+		b.Or(b, big.NewInt(int64(idx)))
+	}
+	checksumBits := len(words) * 11 / 33
+	checksum := new(big.Int).And(b, big.NewInt(int64(1<<checksumBits-1))).Int64()
+	b.Rsh(b, uint(checksumBits))
+
+	entropy := make([]byte, checksumBits*32/8)
+	b.FillBytes(entropy)
+	h := sha256.Sum256(entropy)
+	if int64(h[0]>>(8-checksumBits)) != checksum {
+		return nil, ErrInvalidChecksum
+	}
+	return entropy, nil
+}
+
+func IsValidMnemonic(mnemonic string) bool {
+	_, err := EntropyFromMnemonic(mnemonic)
+	return err == nil
+}
+
+// NewSeed checks the mnemonic and stretches it into a 64 byte seed.
+// BIP39 normalizes the phrase with NFKD. The English word list is ASCII, and
+// passphrases are restricted to ASCII so that no normalization is needed.
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	if _, err := EntropyFromMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	for _, r := range passphrase {
+		if r > 0x7f {
+			return nil, ErrNonASCIIPassphrase
+		}
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(mnemonic), " "))
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase), 2048, 64, sha512.New), nil
+}
@@ -0,0 +1,82 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Test vectors of the reference implementation, all with the passphrase "TREZOR".
+// https://github.com/trezor/python-mnemonic/blob/master/vectors.json
+var bip39Vectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{
+		entropy:  "00000000000000000000000000000000",
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+		seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		entropy:  "80808080808080808080808080808080",
+		mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		seed:     "d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+	},
+	{
+		entropy:  "ffffffffffffffffffffffffffffffff",
+		mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+	{
+		entropy:  "0000000000000000000000000000000000000000000000000000000000000000",
+		mnemonic: strings.Repeat("abandon ", 23) + "art",
+		seed:     "bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+	},
+}
+
+func TestBIP39Vectors(t *testing.T) {
+	for _, tt := range bip39Vectors {
+		entropy, _ := hex.DecodeString(tt.entropy)
+		mnemonic, err := MnemonicFromEntropy(entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mnemonic != tt.mnemonic {
+			t.Errorf("MnemonicFromEntropy(%s) = %q, want %q", tt.entropy, mnemonic, tt.mnemonic)
+		}
+		got, err := EntropyFromMnemonic(tt.mnemonic)
+		if err != nil || hex.EncodeToString(got) != tt.entropy {
+			t.Errorf("EntropyFromMnemonic(%q) = %x, %v, want %s", tt.mnemonic, got, err, tt.entropy)
+		}
+		seed, err := NewSeed(tt.mnemonic, "TREZOR")
+		if err != nil || hex.EncodeToString(seed) != tt.seed {
+			t.Errorf("NewSeed(%q) = %x, %v, want %s", tt.mnemonic, seed, err, tt.seed)
+		}
+	}
+}
+
+func TestMnemonicErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		mnemonic string
+		err      error
+	}{
+		{"checksum", strings.Repeat("abandon ", 11) + "abandon", ErrInvalidChecksum},
+		{"word count", strings.Repeat("abandon ", 10) + "about", ErrInvalidWordCount},
+	}
+	for _, tt := range tests {
+		if _, err := EntropyFromMnemonic(tt.mnemonic); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if _, err := NewSeed(bip39Vectors[0].mnemonic, "パスワード"); !errors.Is(err, ErrNonASCIIPassphrase) {
+		t.Errorf("non-ASCII passphrase: err = %v, want %v", err, ErrNonASCIIPassphrase)
+	}
+}
@@ -0,0 +1,477 @@
+// Package keystore keeps wallet private keys on disk, encrypted with a passphrase.
+// Each key is one JSON file. The key is derived with scrypt (memory-hard) and the
+// private key is sealed with AES-256-GCM.
+// https://github.com/ethereum/wiki/wiki/Web3-Secret-Storage-Definition
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/filestore"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	KDF_SCRYPT     = "scrypt"
+	CIPHER_AES_GCM = "aes-256-gcm"
+
+	// N=2^15, r=8 uses 32MB of memory per derivation.
+	SCRYPT_N     = 1 << 15
+	SCRYPT_R     = 8
+	SCRYPT_P     = 1
+	SCRYPT_DKLEN = 32
+
+	// upper bounds of the parameters read from a key file, so that a crafted file can't take
+	// gigabytes of memory or minutes of CPU. N=2^20, r=16 is 2GB.
+	MAX_SCRYPT_N = 1 << 20
+	MAX_SCRYPT_R = 16
+	MAX_SCRYPT_P = 16
+
+	DEFAULT_UNLOCK_DURATION = 5 * time.Minute
+	MAX_UNLOCK_DURATION     = 24 * time.Hour
+)
+
+var (
+	ErrNotFound          = errors.New("key not found")
+	ErrLocked            = errors.New("key is locked")
+	ErrWrongPassphrase   = errors.New("wrong passphrase")
+	ErrEmptyPassphrase   = errors.New("passphrase is required")
+	ErrInvalidDuration   = fmt.Errorf("unlock duration must be between 1s and %v", MAX_UNLOCK_DURATION)
+	ErrUnsupportedCrypto = errors.New("unsupported kdf or cipher")
+	ErrAlreadyExists     = errors.New("key already exists")
+	ErrKeyChanged        = errors.New("key was changed or deleted meanwhile, try again")
+)
+
+type ScryptParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+type CryptoJSON struct {
+	KDF        string       `json:"kdf"`
+	KDFParams  ScryptParams `json:"kdfparams"`
+	Cipher     string       `json:"cipher"`
+	Nonce      string       `json:"nonce"`
+	Ciphertext string       `json:"ciphertext"`
+}
+
+// KeyFile is the content of one keystore file. Only the private key is encrypted.
+type KeyFile struct {
+	ID                string     `json:"id"`
+	BlockchainAddress string     `json:"blockchain_address"`
+	PublicKey         string     `json:"public_key"`
+	CreatedAt         time.Time  `json:"created_at"`
+	Crypto            CryptoJSON `json:"crypto"`
+}
+
+type unlockedKey struct {
+	wallet *model.Wallet
+	until  time.Time
+	timer  *time.Timer
+}
+
+type Keystore struct {
+	dir string
+
+	mux      sync.Mutex
+	unlocked map[string]*unlockedKey
+}
+
+func New(dir string) (*Keystore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Keystore{
+		dir:      dir,
+		unlocked: make(map[string]*unlockedKey),
+	}, nil
+}
+
+// Create generates a new key of scheme and stores it encrypted with passphrase.
+// A nil scheme is ECDSA P-256.
+func (ks *Keystore) Create(passphrase string, scheme common.SignatureScheme) (*KeyFile, error) {
+	w, err := model.NewSchemeWallet(scheme)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate a key: %w", err)
+	}
+	kf, err := newKeyFile(w, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	if err := ks.write(kf); err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+// Import stores an existing key encrypted with passphrase.
+// Restore the wallet with model.ParsePrivateKey, which derives the public key and the address.
+// The lock is held from the check to the write, so two imports of the same key can't both be stored.
+func (ks *Keystore) Import(w *model.Wallet, passphrase string) (*KeyFile, error) {
+	kf, err := newKeyFile(w, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	if _, err := ks.find(w.BlockchainAddress()); err == nil {
+		return nil, ErrAlreadyExists
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err := ks.write(kf); err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+// newKeyFile encrypts the key of w. It is slow on purpose (scrypt), so it runs without the lock.
+func newKeyFile(w *model.Wallet, passphrase string) (*KeyFile, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	kf := &KeyFile{
+		ID:                id,
+		BlockchainAddress: w.BlockchainAddress(),
+		PublicKey:         w.PublicKeyStr(),
+		CreatedAt:         time.Now().UTC(),
+	}
+	if err := kf.seal(w.PrivateKeyBytes(), passphrase); err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+func (ks *Keystore) List() ([]*KeyFile, error) {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	return ks.list()
+}
+
+func (ks *Keystore) list() ([]*KeyFile, error) {
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*KeyFile, 0, len(paths))
+	for _, p := range paths {
+		kf, err := readKeyFile(p)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, kf)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Find looks a key up by its ID or its blockchain address.
+func (ks *Keystore) Find(idOrAddress string) (*KeyFile, error) {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	return ks.find(idOrAddress)
+}
+
+func (ks *Keystore) find(idOrAddress string) (*KeyFile, error) {
+	keys, err := ks.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, kf := range keys {
+		if kf.ID == idOrAddress || kf.BlockchainAddress == idOrAddress {
+			return kf, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Unlock decrypts the key and keeps it in memory for d.
+func (ks *Keystore) Unlock(idOrAddress, passphrase string, d time.Duration) (time.Time, error) {
+	if d == 0 {
+		d = DEFAULT_UNLOCK_DURATION
+	}
+	if d < time.Second || d > MAX_UNLOCK_DURATION {
+		return time.Time{}, ErrInvalidDuration
+	}
+	kf, err := ks.Find(idOrAddress)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// scrypt is slow on purpose, so the key is derived without the lock
+	w, err := kf.open(passphrase)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	if err := ks.unchanged(kf); err != nil {
+		return time.Time{}, err
+	}
+	ks.lock(kf.ID)
+	id := kf.ID
+	u := &unlockedKey{wallet: w, until: time.Now().Add(d)}
+	u.timer = time.AfterFunc(d, func() {
+		ks.mux.Lock()
+		defer ks.mux.Unlock()
+		// the key may have been unlocked again in the meantime.
+		if ks.unlocked[id] == u {
+			delete(ks.unlocked, id)
+		}
+	})
+	ks.unlocked[id] = u
+	return u.until, nil
+}
+
+func (ks *Keystore) Lock(id string) {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	ks.lock(id)
+}
+
+func (ks *Keystore) lock(id string) {
+	if u, ok := ks.unlocked[id]; ok {
+		u.timer.Stop()
+		delete(ks.unlocked, id)
+	}
+}
+
+// UnlockedUntil returns when the key will be locked again, or the zero time if it is locked.
+func (ks *Keystore) UnlockedUntil(id string) time.Time {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	if u, ok := ks.unlocked[id]; ok {
+		return u.until
+	}
+	return time.Time{}
+}
+
+// Wallet returns the unlocked wallet for signing.
+func (ks *Keystore) Wallet(idOrAddress string) (*model.Wallet, error) {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	kf, err := ks.find(idOrAddress)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := ks.unlocked[kf.ID]
+	if !ok {
+		return nil, ErrLocked
+	}
+	return u.wallet, nil
+}
+
+// Open decrypts the key once without keeping it unlocked.
+func (ks *Keystore) Open(idOrAddress, passphrase string) (*model.Wallet, error) {
+	kf, err := ks.Find(idOrAddress)
+	if err != nil {
+		return nil, err
+	}
+	return kf.open(passphrase)
+}
+
+// ChangePassphrase re-encrypts the key with a fresh salt and nonce.
+func (ks *Keystore) ChangePassphrase(idOrAddress, passphrase, newPassphrase string) error {
+	if newPassphrase == "" {
+		return ErrEmptyPassphrase
+	}
+	kf, err := ks.Find(idOrAddress)
+	if err != nil {
+		return err
+	}
+	w, err := kf.open(passphrase)
+	if err != nil {
+		return err
+	}
+	changed := *kf
+	if err := changed.seal(w.PrivateKeyBytes(), newPassphrase); err != nil {
+		return err
+	}
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	if err := ks.unchanged(kf); err != nil {
+		return err
+	}
+	return ks.write(&changed)
+}
+
+// Delete removes the key file. The passphrase is required so that a key can't be lost by mistake.
+func (ks *Keystore) Delete(idOrAddress, passphrase string) error {
+	kf, err := ks.Find(idOrAddress)
+	if err != nil {
+		return err
+	}
+	if _, err := kf.open(passphrase); err != nil {
+		return err
+	}
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	if err := ks.unchanged(kf); err != nil {
+		return err
+	}
+	ks.lock(kf.ID)
+	return os.Remove(ks.path(kf.ID))
+}
+
+// unchanged tells whether the file of kf is still the one that was read before the key was derived.
+// The caller holds ks.mux.
+func (ks *Keystore) unchanged(kf *KeyFile) error {
+	current, err := readKeyFile(ks.path(kf.ID))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrKeyChanged
+	} else if err != nil {
+		return err
+	}
+	if current.Crypto != kf.Crypto {
+		return ErrKeyChanged
+	}
+	return nil
+}
+
+func (ks *Keystore) path(id string) string {
+	return filepath.Join(ks.dir, id+".json")
+}
+
+func (ks *Keystore) write(kf *KeyFile) error {
+	return filestore.WriteJSON(ks.path(kf.ID), kf)
+}
+
+func readKeyFile(path string) (*KeyFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var kf KeyFile
+	if err := json.Unmarshal(b, &kf); err != nil {
+		return nil, fmt.Errorf("keystore %s: %w", path, err)
+	}
+	return &kf, nil
+}
+
+// seal encrypts the 32 bytes of the private key of any scheme.
+func (kf *KeyFile) seal(privateKey []byte, passphrase string) error {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	params := ScryptParams{N: SCRYPT_N, R: SCRYPT_R, P: SCRYPT_P, DKLen: SCRYPT_DKLEN, Salt: hex.EncodeToString(salt)}
+	aead, err := params.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	ciphertext := aead.Seal(nil, nonce, privateKey, kf.additionalData())
+	kf.Crypto = CryptoJSON{
+		KDF:        KDF_SCRYPT,
+		KDFParams:  params,
+		Cipher:     CIPHER_AES_GCM,
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(ciphertext),
+	}
+	return nil
+}
+
+func (kf *KeyFile) open(passphrase string) (*model.Wallet, error) {
+	c := kf.Crypto
+	if c.KDF != KDF_SCRYPT || c.Cipher != CIPHER_AES_GCM {
+		return nil, ErrUnsupportedCrypto
+	}
+	if err := c.KDFParams.validate(); err != nil {
+		return nil, err
+	}
+	aead, err := c.KDFParams.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(c.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(c.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, ErrUnsupportedCrypto
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, kf.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	// the address tells the scheme of the key
+	scheme, err := common.SchemeOfAddress(kf.BlockchainAddress)
+	if err != nil {
+		return nil, err
+	}
+	w, err := model.NewSchemeWalletFromPrivateKey(scheme, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if w.BlockchainAddress() != kf.BlockchainAddress {
+		return nil, fmt.Errorf("keystore %s: address does not match the key", kf.ID)
+	}
+	return w, nil
+}
+
+// additionalData binds the ciphertext to the file, so it can't be moved to another key.
+func (kf *KeyFile) additionalData() []byte {
+	return []byte(kf.ID + ":" + kf.BlockchainAddress + ":" + kf.PublicKey)
+}
+
+func (p ScryptParams) validate() error {
+	if p.N > MAX_SCRYPT_N || p.R > MAX_SCRYPT_R || p.P > MAX_SCRYPT_P || p.DKLen != SCRYPT_DKLEN {
+		return fmt.Errorf("%w: scrypt n=%d r=%d p=%d dklen=%d", ErrUnsupportedCrypto, p.N, p.R, p.P, p.DKLen)
+	}
+	return nil
+}
+
+func (p ScryptParams) aead(passphrase string) (cipher.AEAD, error) {
+	salt, err := hex.DecodeString(p.Salt)
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, p.N, p.R, p.P, p.DKLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newID returns a random UUID (version 4).
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
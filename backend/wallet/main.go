@@ -2,12 +2,41 @@ package main
 
 import (
 	"log"
+	"os"
 
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/addressbook"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/config"
 	"github.com/yagikota/blockchain_with_go/backend/wallet/controller"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/keystore"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/watchonly"
 )
 
 // https://docs.gofiber.io/api/app#group
 func main() {
-	app1 := controller.InitRouter()
-	log.Fatal(app1.Listen(":8000"))
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	network, err := common.NetworkByName(cfg.Network)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// addresses of the other networks are invalid from here on
+	network.Use()
+	log.Printf("network %v blockchain nodes %v", network.Name, cfg.Nodes)
+	ks, err := keystore.New(cfg.KeystoreDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ws, err := watchonly.New(cfg.WatchFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ab, err := addressbook.New(cfg.AddressBook)
+	if err != nil {
+		log.Fatal(err)
+	}
+	app1 := controller.InitRouter(cfg, ks, ws, ab)
+	log.Fatal(app1.Listen(cfg.ListenAddress))
 }
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+// Payout is one recipient of a batch transaction, an address or a contact name.
+type Payout struct {
+	RecipientBlockchainAddress string  `json:"recipient_blockchain_address"`
+	RecipientContact           string  `json:"recipient_contact"`
+	Value                      float64 `json:"value"`
+}
+
+func (p Payout) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.RecipientBlockchainAddress, validation.Required.When(p.RecipientContact == ""), validation.Length(26, 35)),
+		validation.Field(&p.Value, validation.Required, validation.Min(0.0).Exclusive()),
+	)
+}
+
+// 署名前のbatch transaction。CSVの場合はsender_*をquery parameterで渡す。
+type PrepareBatchTransactionRequest struct {
+	SenderPublicKey         string    `json:"sender_public_key" query:"sender_public_key"`
+	SenderBlockchainAddress string    `json:"sender_blockchain_address" query:"sender_blockchain_address"`
+	Outputs                 []*Payout `json:"outputs" query:"-"`
+}
+
+func (t PrepareBatchTransactionRequest) Validate() error {
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.SenderPublicKey, validation.Required, publicKeyRule(t.SenderBlockchainAddress)),
+		validation.Field(&t.SenderBlockchainAddress, validation.Required, validation.Length(26, 35)),
+		validation.Field(&t.Outputs, validation.Required, validation.Length(1, common.MAX_TRANSACTION_OUTPUTS)),
+	)
+}
+
+// 署名済み、またはkeystoreの鍵でserverが署名するbatch transaction。
+type BatchTransactionRequest struct {
+	SenderWallet            string    `json:"sender_wallet" query:"sender_wallet"`
+	SenderPublicKey         string    `json:"sender_public_key" query:"sender_public_key"`
+	SenderBlockchainAddress string    `json:"sender_blockchain_address" query:"sender_blockchain_address"`
+	Outputs                 []*Payout `json:"outputs" query:"-"`
+	Signature               string    `json:"signature" query:"signature"`
+	SignatureScheme         string    `json:"signature_scheme" query:"signature_scheme"`
+	Nonce                   uint64    `json:"nonce" query:"nonce"`
+}
+
+func (t BatchTransactionRequest) Validate() error {
+	signedByClient := t.SenderWallet == ""
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.SenderPublicKey, validation.When(signedByClient, validation.Required, publicKeyRule(t.SenderBlockchainAddress))),
+		validation.Field(&t.SenderBlockchainAddress, validation.When(signedByClient, validation.Required, validation.Length(26, 35))),
+		validation.Field(&t.Outputs, validation.Required, validation.Length(1, common.MAX_TRANSACTION_OUTPUTS)),
+		validation.Field(&t.Signature, validation.When(signedByClient, validation.Required, signatureRule(t.SignatureScheme, t.SenderBlockchainAddress))),
+		validation.Field(&t.SignatureScheme, validation.When(signedByClient, signatureSchemeRule(t.SenderBlockchainAddress))),
+	)
+}
+
+// ParsePayoutsCSV reads "recipient,value" rows. The recipient is a blockchain address
+// or a contact name of the address book. A header row is skipped.
+func ParsePayoutsCSV(r io.Reader) ([]*Payout, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	var payouts []*Payout
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: recipient and value are required", line)
+		}
+		recipient := strings.TrimSpace(record[0])
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: invalid value %q", line, record[1])
+		}
+		p := &Payout{Value: value}
+		if looksLikeAddress(recipient) {
+			p.RecipientBlockchainAddress = recipient
+		} else {
+			p.RecipientContact = recipient
+		}
+		payouts = append(payouts, p)
+	}
+	return payouts, nil
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// looksLikeAddress tells an address (which may still have a bad checksum) from a contact name.
+func looksLikeAddress(s string) bool {
+	if len(s) < 26 || len(s) > 35 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune(base58Alphabet, r) {
+			return false
+		}
+	}
+	return true
+}
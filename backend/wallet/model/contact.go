@@ -0,0 +1,26 @@
+package model
+
+import (
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// contact nameはURLのpathに入るので"/"は使えない。
+var contactNamePattern = regexp.MustCompile(`^[^/]+$`)
+
+type ContactRequest struct {
+	Name              string   `json:"name"`
+	BlockchainAddress string   `json:"blockchain_address"`
+	Notes             string   `json:"notes"`
+	Tags              []string `json:"tags"`
+}
+
+func (r ContactRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Name, validation.Required, validation.Length(1, 64), validation.Match(contactNamePattern)),
+		validation.Field(&r.BlockchainAddress, validation.Required, validation.Length(26, 35)),
+		validation.Field(&r.Notes, validation.Length(0, 1000)),
+		validation.Field(&r.Tags, validation.Each(validation.Length(1, 32))),
+	)
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+const (
+	HD_DEFAULT_ADDRESS_COUNT = 5
+	HD_MAX_ADDRESS_COUNT     = 100
+	HD_DEFAULT_GAP_LIMIT     = 20
+	HD_MAX_SCAN_ADDRESSES    = 1000
+)
+
+// HDWalletRequest generates a new mnemonic, or restores from Mnemonic when it is given.
+type HDWalletRequest struct {
+	Mnemonic     string `json:"mnemonic"`
+	Passphrase   string `json:"passphrase"`
+	WordCount    int    `json:"word_count"`   // 12, 15, 18, 21 or 24. default 12.
+	AccountPath  string `json:"account_path"` // default m/44'/0'/0'/0
+	AddressCount int    `json:"address_count"`
+}
+
+func (r HDWalletRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.WordCount, validation.In(0, 12, 15, 18, 21, 24)),
+		validation.Field(&r.AddressCount, validation.Min(0), validation.Max(HD_MAX_ADDRESS_COUNT)),
+	)
+}
+
+type HDWalletResponse struct {
+	Mnemonic          string           `json:"mnemonic,omitempty"` // only when it was generated
+	AccountPath       string           `json:"account_path"`
+	ExtendedPublicKey string           `json:"extended_public_key"` // watch-only, derives the addresses below
+	Addresses         []DerivedAddress `json:"addresses"`
+}
+
+type DerivedAddress struct {
+	Index             uint32   `json:"index"`
+	PublicKey         string   `json:"public_key"`
+	BlockchainAddress string   `json:"blockchain_address"`
+	Amount            *float64 `json:"amount,omitempty"`
+}
+
+type HDDeriveRequest struct {
+	ExtendedPublicKey string `json:"extended_public_key"`
+	Start             uint32 `json:"start"`
+	Count             int    `json:"count"`
+}
+
+func (r HDDeriveRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ExtendedPublicKey, validation.Required),
+		validation.Field(&r.Count, validation.Min(0), validation.Max(HD_MAX_ADDRESS_COUNT)),
+	)
+}
+
+// HDScanRequest scans child addresses until GapLimit addresses in a row have no balance.
+type HDScanRequest struct {
+	ExtendedPublicKey string `json:"extended_public_key"`
+	GapLimit          int    `json:"gap_limit"`
+}
+
+func (r HDScanRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ExtendedPublicKey, validation.Required),
+		validation.Field(&r.GapLimit, validation.Min(0), validation.Max(HD_MAX_ADDRESS_COUNT)),
+	)
+}
+
+type HDScanResponse struct {
+	Addresses   []DerivedAddress `json:"addresses"` // addresses with a balance
+	TotalAmount float64          `json:"total_amount"`
+	Scanned     int              `json:"scanned"`
+}
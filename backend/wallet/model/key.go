@@ -0,0 +1,280 @@
+package model
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+// private key formats for import and export.
+const (
+	KEY_FORMAT_HEX = "hex" // 32 bytes, no checksum. The seed of an Ed25519 key.
+	KEY_FORMAT_WIF = "wif" // Wallet Import Format: base58(version || key || [0x01] || checksum). ECDSA only. The version is common.WIFVersion.
+	KEY_FORMAT_PEM = "pem" // PKCS#8 "PRIVATE KEY". SEC1 "EC PRIVATE KEY" is accepted on import.
+
+	// wifCompressed follows the key in the WIF of a secp256k1 key, as Bitcoin writes keys
+	// of compressed public keys. P-256 keys are written without it.
+	wifCompressed = 0x01
+
+	pemTypePKCS8 = "PRIVATE KEY"
+	pemTypeSEC1  = "EC PRIVATE KEY"
+)
+
+var KeyFormats = []interface{}{KEY_FORMAT_HEX, KEY_FORMAT_WIF, KEY_FORMAT_PEM}
+
+var (
+	ErrInvalidWIF       = errors.New("invalid WIF: bad length or checksum")
+	ErrWIFNetwork       = errors.New("WIF is for another network")
+	ErrInvalidPEM       = errors.New("invalid PEM private key")
+	ErrUnsupportedKey   = errors.New("only P-256 and secp256k1 ECDSA keys and Ed25519 keys are supported")
+	ErrWIFScheme        = errors.New("WIF is only for ECDSA keys")
+	ErrSchemeMismatch   = errors.New("the key is of another signature scheme")
+	ErrUnknownKeyFormat = fmt.Errorf("unknown key format, one of %v", KeyFormats)
+)
+
+// OIDs of the keys in PKCS#8 and SEC1. crypto/x509 doesn't know secp256k1,
+// so the DER is read and written here.
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidNamedCurveP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidNamedCurveK256 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+	oidEd25519        = asn1.ObjectIdentifier{1, 3, 101, 112} // RFC 8410
+	schemeByCurveOID  = map[string]string{
+		oidNamedCurveP256.String(): common.SCHEME_ECDSA_P256,
+		oidNamedCurveK256.String(): common.SCHEME_ECDSA_SECP256K1,
+	}
+)
+
+// pkcs8 and ecPrivateKey are the ASN.1 structures of RFC 5208 and RFC 5915.
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// ParsePrivateKey restores a wallet from an exported private key.
+// An empty format detects it from the string. The public key and the address
+// are always derived from the private key.
+//
+// A nil scheme is detected as well: PEM names its algorithm and curve, a WIF with the
+// compression flag is secp256k1 as written by Bitcoin tooling, and anything else is ECDSA P-256.
+// Hex of an Ed25519 seed can't be told from a P-256 key, so its scheme must be given.
+func ParsePrivateKey(s, format string, scheme common.SignatureScheme) (*Wallet, error) {
+	s = strings.TrimSpace(s)
+	if format == "" {
+		format = detectKeyFormat(s)
+	}
+	var d []byte
+	var detected string
+	var err error
+	switch format {
+	case KEY_FORMAT_HEX:
+		d, err = hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil || len(d) != 32 {
+			return nil, common.ErrInvalidPrivateKey
+		}
+	case KEY_FORMAT_WIF:
+		if scheme != nil && scheme.ID() == common.SCHEME_ED25519 {
+			return nil, ErrWIFScheme
+		}
+		d, detected, err = decodeWIF(s)
+	case KEY_FORMAT_PEM:
+		d, detected, err = decodePEM(s)
+	default:
+		return nil, ErrUnknownKeyFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	// PEM is explicit about the scheme, so it must agree with the requested one.
+	if format == KEY_FORMAT_PEM && scheme != nil && scheme.ID() != detected {
+		return nil, fmt.Errorf("%w: %s", ErrSchemeMismatch, detected)
+	}
+	if scheme == nil {
+		if scheme, err = common.SchemeByID(detected); err != nil {
+			return nil, err
+		}
+	}
+	return NewSchemeWalletFromPrivateKey(scheme, d)
+}
+
+func detectKeyFormat(s string) string {
+	if strings.HasPrefix(s, "-----BEGIN") {
+		return KEY_FORMAT_PEM
+	}
+	if _, err := hex.DecodeString(strings.TrimPrefix(s, "0x")); err == nil {
+		return KEY_FORMAT_HEX
+	}
+	return KEY_FORMAT_WIF
+}
+
+// ExportPrivateKey encodes the private key of the wallet in format.
+func (w *Wallet) ExportPrivateKey(format string) (string, error) {
+	switch format {
+	case KEY_FORMAT_HEX:
+		return w.PrivateKeyStr(), nil
+	case KEY_FORMAT_WIF:
+		if w.Curve() == "" {
+			return "", ErrWIFScheme
+		}
+		return w.WIF(), nil
+	case KEY_FORMAT_PEM:
+		return w.PEM()
+	}
+	return "", ErrUnknownKeyFormat
+}
+
+// WIF encodes the private key with the network prefix and a 4 byte checksum.
+// It is empty for keys other than ECDSA.
+// https://en.bitcoin.it/wiki/Wallet_import_format
+func (w *Wallet) WIF() string {
+	if w.Curve() == "" {
+		return ""
+	}
+	b := make([]byte, 0, 1+32+1+4)
+	b = append(b, common.WIFVersion())
+	b = append(b, w.PrivateKeyBytes()...)
+	if w.Curve() == common.CURVE_SECP256K1 {
+		b = append(b, wifCompressed)
+	}
+	return base58.Encode(append(b, checksum(b)...))
+}
+
+// decodeWIF returns the key and the scheme told by the compression flag.
+func decodeWIF(s string) ([]byte, string, error) {
+	b := base58.Decode(s)
+	if len(b) != 1+32+4 && len(b) != 1+32+1+4 {
+		return nil, "", ErrInvalidWIF
+	}
+	payload, sum := b[:len(b)-4], b[len(b)-4:]
+	if !bytes.Equal(checksum(payload), sum) {
+		return nil, "", ErrInvalidWIF
+	}
+	if payload[0] != common.WIFVersion() {
+		return nil, "", ErrWIFNetwork
+	}
+	if len(payload) == 1+32+1 {
+		if payload[33] != wifCompressed {
+			return nil, "", ErrInvalidWIF
+		}
+		return payload[1:33], common.SCHEME_ECDSA_SECP256K1, nil
+	}
+	return payload[1:], common.SCHEME_ECDSA_P256, nil
+}
+
+// PEM encodes the private key as PKCS#8, which openssl and most libraries can read.
+func (w *Wallet) PEM() (string, error) {
+	var der []byte
+	var err error
+	if w.Curve() == common.CURVE_SECP256K1 {
+		der, err = marshalPKCS8(w.privateKey.(*ecdsa.PrivateKey), oidNamedCurveK256)
+	} else {
+		// P-256 and Ed25519
+		der, err = x509.MarshalPKCS8PrivateKey(w.privateKey)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: pemTypePKCS8, Bytes: der})), nil
+}
+
+func marshalPKCS8(privateKey *ecdsa.PrivateKey, curveOID asn1.ObjectIdentifier) ([]byte, error) {
+	params, err := asn1.Marshal(curveOID)
+	if err != nil {
+		return nil, err
+	}
+	publicKey := append([]byte{0x04}, privateKey.X.FillBytes(make([]byte, 32))...)
+	publicKey = append(publicKey, privateKey.Y.FillBytes(make([]byte, 32))...)
+	inner, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: privateKey.D.FillBytes(make([]byte, 32)),
+		PublicKey:  asn1.BitString{Bytes: publicKey, BitLength: len(publicKey) * 8},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{
+		Algo: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPublicKeyECDSA,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		PrivateKey: inner,
+	})
+}
+
+// decodePEM reads PKCS#8 or SEC1 and returns the private key bytes and the ID of its scheme.
+// Only the private key is used; the public key in the file is ignored.
+func decodePEM(s string) ([]byte, string, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, "", ErrInvalidPEM
+	}
+	var curveOID asn1.ObjectIdentifier
+	der := block.Bytes
+	switch block.Type {
+	case pemTypePKCS8:
+		var k pkcs8
+		if _, err := asn1.Unmarshal(der, &k); err != nil {
+			return nil, "", ErrInvalidPEM
+		}
+		if k.Algo.Algorithm.Equal(oidEd25519) {
+			// CurvePrivateKey ::= OCTET STRING of the 32 bytes seed
+			var seed []byte
+			if rest, err := asn1.Unmarshal(k.PrivateKey, &seed); err != nil || len(rest) > 0 || len(seed) != 32 {
+				return nil, "", ErrInvalidPEM
+			}
+			return seed, common.SCHEME_ED25519, nil
+		}
+		if !k.Algo.Algorithm.Equal(oidPublicKeyECDSA) {
+			return nil, "", ErrUnsupportedKey
+		}
+		if _, err := asn1.Unmarshal(k.Algo.Parameters.FullBytes, &curveOID); err != nil {
+			return nil, "", ErrInvalidPEM
+		}
+		der = k.PrivateKey
+	case pemTypeSEC1:
+	default:
+		return nil, "", ErrInvalidPEM
+	}
+	var k ecPrivateKey
+	if _, err := asn1.Unmarshal(der, &k); err != nil || k.Version != 1 || len(k.PrivateKey) > 32 {
+		return nil, "", ErrInvalidPEM
+	}
+	if len(k.NamedCurveOID) > 0 {
+		if len(curveOID) > 0 && !curveOID.Equal(k.NamedCurveOID) {
+			return nil, "", ErrInvalidPEM
+		}
+		curveOID = k.NamedCurveOID
+	}
+	scheme, ok := schemeByCurveOID[curveOID.String()]
+	if !ok {
+		return nil, "", ErrUnsupportedKey
+	}
+	d := make([]byte, 32)
+	copy(d[32-len(k.PrivateKey):], k.PrivateKey)
+	return d, scheme, nil
+}
+
+func checksum(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:4]
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+type KeystoreCreateRequest struct {
+	Passphrase string `json:"passphrase"`
+	Curve      string `json:"curve"`            // P-256 or secp256k1, the ECDSA scheme on the curve
+	Scheme     string `json:"signature_scheme"` // ecdsa-p256 when both are empty
+}
+
+func (r KeystoreCreateRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Passphrase, validation.Required, validation.Length(8, 0)),
+		validation.Field(&r.Curve, validation.In(common.Curves...)),
+		validation.Field(&r.Scheme, validation.In(common.SignatureSchemes...)),
+	)
+}
+
+type KeystoreUnlockRequest struct {
+	Passphrase  string `json:"passphrase"`
+	DurationSec int    `json:"duration_sec"` // 0 means the default duration
+}
+
+func (r KeystoreUnlockRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Passphrase, validation.Required),
+		validation.Field(&r.DurationSec, validation.Min(0)),
+	)
+}
+
+type KeystorePassphraseRequest struct {
+	Passphrase    string `json:"passphrase"`
+	NewPassphrase string `json:"new_passphrase"`
+}
+
+func (r KeystorePassphraseRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Passphrase, validation.Required),
+		validation.Field(&r.NewPassphrase, validation.Required, validation.Length(8, 0)),
+	)
+}
+
+type KeystoreDeleteRequest struct {
+	Passphrase string `json:"passphrase"`
+}
+
+func (r KeystoreDeleteRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Passphrase, validation.Required),
+	)
+}
+
+type KeyImportRequest struct {
+	PrivateKey string `json:"private_key"`
+	Format     string `json:"format"`           // hex, wif or pem. detected when empty.
+	Curve      string `json:"curve"`            // P-256 or secp256k1. detected when empty.
+	Scheme     string `json:"signature_scheme"` // detected when empty, except for hex of an Ed25519 seed
+	Passphrase string `json:"passphrase"`
+}
+
+func (r KeyImportRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.PrivateKey, validation.Required),
+		validation.Field(&r.Format, validation.In(KeyFormats...)),
+		validation.Field(&r.Curve, validation.In(common.Curves...)),
+		validation.Field(&r.Scheme, validation.In(common.SignatureSchemes...)),
+		validation.Field(&r.Passphrase, validation.Required, validation.Length(8, 0)),
+	)
+}
+
+type KeyExportRequest struct {
+	Format     string `json:"format"`
+	Passphrase string `json:"passphrase"`
+}
+
+func (r KeyExportRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Format, validation.Required, validation.In(KeyFormats...)),
+		validation.Field(&r.Passphrase, validation.Required),
+	)
+}
+
+type KeyExportResponse struct {
+	Format            string `json:"format"`
+	PrivateKey        string `json:"private_key"`
+	PublicKey         string `json:"public_key"`
+	BlockchainAddress string `json:"blockchain_address"`
+}
+
+// KeyResponse never contains the private key.
+type KeyResponse struct {
+	ID                string     `json:"id"`
+	BlockchainAddress string     `json:"blockchain_address"`
+	PublicKey         string     `json:"public_key"`
+	Curve             string     `json:"curve,omitempty"` // ECDSA only
+	SignatureScheme   string     `json:"signature_scheme"`
+	CreatedAt         time.Time  `json:"created_at"`
+	Unlocked          bool       `json:"unlocked"`
+	UnlockedUntil     *time.Time `json:"unlocked_until,omitempty"`
+}
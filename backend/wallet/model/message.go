@@ -0,0 +1,40 @@
+package model
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+const (
+	SIGNATURE_ENCODING_HEX = "hex" // hex of the signature, R || S for ECDSA
+	SIGNATURE_ENCODING_DER = "der" // hex of ASN.1 DER, ECDSA only
+)
+
+// keystoreの鍵でmessageに署名する。sender_walletはunlockされている必要がある。
+type MessageSignRequest struct {
+	SenderWallet string `json:"sender_wallet"`
+	Message      string `json:"message"`
+	Encoding     string `json:"encoding"` // hex when empty
+}
+
+func (r MessageSignRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.SenderWallet, validation.Required),
+		validation.Field(&r.Message, validation.By(validateMessage)),
+		validation.Field(&r.Encoding, validation.In(SIGNATURE_ENCODING_HEX, SIGNATURE_ENCODING_DER)),
+	)
+}
+
+// MessageSignResponse has every field that POST /v1/messages/verify of the node needs.
+type MessageSignResponse struct {
+	BlockchainAddress string `json:"blockchain_address"`
+	PublicKey         string `json:"public_key"`
+	Message           string `json:"message"`
+	Signature         string `json:"signature"`
+	SignatureScheme   string `json:"signature_scheme"`
+}
+
+func validateMessage(v interface{}) error {
+	s, _ := v.(string)
+	return common.ValidateMessage(s)
+}
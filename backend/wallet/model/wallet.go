@@ -1,100 +1,193 @@
 package model
 
 import (
+	"crypto"
 	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
-	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
 
-	"github.com/btcsuite/btcd/btcutil/base58"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/yagikota/blockchain_with_go/backend/common"
-	"golang.org/x/crypto/ripemd160"
 )
 
 type Wallet struct {
-	privateKey        *ecdsa.PrivateKey
-	publicKey         *ecdsa.PublicKey
+	scheme            common.SignatureScheme
+	privateKey        crypto.Signer
+	publicKey         crypto.PublicKey
 	blockchainAddress string
 }
 
-// https://en.bitcoin.it/wiki/Technical_background_of_version_1_Bitcoin_addresses
+// NewWallet creates an ECDSA P-256 wallet, the scheme of the wallets created before the others.
 func NewWallet() *Wallet {
-	// 1. create ECDSA private key (32 bytes), public key (64 bytes).
-	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	w, _ := NewSchemeWallet(nil)
+	return w
+}
+
+// NewSchemeWallet creates a wallet with a new key of scheme, ECDSA P-256 when nil.
+// The scheme decides the version byte of the address.
+// https://en.bitcoin.it/wiki/Technical_background_of_version_1_Bitcoin_addresses
+func NewSchemeWallet(scheme common.SignatureScheme) (*Wallet, error) {
+	if scheme == nil {
+		scheme, _ = common.SchemeByID(common.SCHEME_ECDSA_P256)
+	}
+	privateKey, err := scheme.GenerateKey()
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	w := &Wallet{
-		privateKey: privateKey,
-		publicKey:  &privateKey.PublicKey,
+	return newWallet(scheme, privateKey), nil
+}
+
+// NewSchemeWalletFromPrivateKey restores a wallet from the bytes of scheme.PrivateKeyBytes.
+func NewSchemeWalletFromPrivateKey(scheme common.SignatureScheme, d []byte) (*Wallet, error) {
+	privateKey, err := scheme.PrivateKeyFromBytes(d)
+	if err != nil {
+		return nil, err
 	}
+	return newWallet(scheme, privateKey), nil
+}
 
-	// create blockchainAddress from publicKey
-	// 2.Perform SHA-256 hashing on the public key (32 bytes).
-	h2 := sha256.New()
-	h2.Write(w.publicKey.X.Bytes())
-	h2.Write(w.publicKey.Y.Bytes())
-	digest2 := h2.Sum(nil)
-	// 3. Perform RIPEMD-160 hashing on the result of SHA-256 (20 bytes)
-	h3 := ripemd160.New()
-	h3.Write(digest2)
-	digest3 := h3.Sum(nil) // digest3(20bytes)
-	// 4. Add network ID byte in front of RIPEMD-160 hash (0x00 for Main Network).
-	digest4 := make([]byte, len(digest3)+1)
-	digest4[0] = 0x00
-	copy(digest4[1:], digest3)
-	// 5. Perform SHA-256 hash on the extended RIPEMD-160 result.
-	h5 := sha256.New()
-	h5.Write(digest4)
-	digest5 := h5.Sum(nil)
-	// 6. Perform SHA-256 hash on the result of the previous SHA-256 hash.
-	h6 := sha256.New()
-	h6.Write(digest5)
-	digest6 := h6.Sum(nil)
-	// 7. Take the first 4 bytes of the second SHA-256 hash for checksum.
-	checkSum := digest6[:4]
-	// 8. Add the 4 checksum bytes from 7 at the end of extended RIPEMD-160 hash from 4 (25 bytes).
-	digestCheckSum := make([]byte, len(digest4)+len(checkSum))
-	copy(digestCheckSum[:len(digest4)], digest4)
-	copy(digestCheckSum[len(digest4):], checkSum)
-	// 9. Convert the result from a byte string into base58.
-	address := base58.Encode(digestCheckSum)
-	w.blockchainAddress = address
-	return w
+func newWallet(scheme common.SignatureScheme, privateKey crypto.Signer) *Wallet {
+	publicKey := privateKey.Public()
+	return &Wallet{
+		scheme:            scheme,
+		privateKey:        privateKey,
+		publicKey:         publicKey,
+		blockchainAddress: scheme.Address(publicKey),
+	}
+}
+
+// AddressFromPublicKey creates blockchainAddress from publicKey.
+func AddressFromPublicKey(publicKey crypto.PublicKey) string {
+	return common.AddressFromPublicKey(publicKey)
+}
+
+var (
+	ErrInvalidAddress   = common.ErrInvalidAddress
+	ErrInvalidPublicKey = common.ErrInvalidPublicKey
+)
+
+// signatureRule checks a signature sent by a client in the scheme of the transaction.
+// An empty value is left to validation.Required, and an invalid scheme or address to their rules.
+func signatureRule(schemeID, address string) validation.Rule {
+	return validation.By(func(v interface{}) error {
+		s, _ := v.(string)
+		scheme, err := common.SchemeOfTransaction(schemeID, address)
+		if s == "" || err != nil {
+			return nil
+		}
+		_, err = scheme.ParseSignature(s)
+		return err
+	})
 }
 
-func (w *Wallet) PrivateKey() *ecdsa.PrivateKey {
+// publicKeyRule checks a public key of the scheme of the address it belongs to.
+// An invalid address is left to the rules of the address.
+func publicKeyRule(address string) validation.Rule {
+	return validation.By(func(v interface{}) error {
+		s, _ := v.(string)
+		if s == "" {
+			return nil
+		}
+		if _, err := common.ParsePublicKeyForAddress(s, address); err != nil && err != common.ErrInvalidAddress {
+			return err
+		}
+		return nil
+	})
+}
+
+// signatureSchemeRule checks that the scheme is known and is the scheme of the address.
+func signatureSchemeRule(address string) validation.Rule {
+	return validation.By(func(v interface{}) error {
+		id, _ := v.(string)
+		if id == "" {
+			return nil
+		}
+		if _, err := common.SchemeByID(id); err != nil {
+			return err
+		}
+		if _, err := common.SchemeOfTransaction(id, address); err == common.ErrSchemeMismatch {
+			return err
+		}
+		return nil
+	})
+}
+
+// ValidateAddress checks the version byte and the checksum of a blockchain address.
+func ValidateAddress(address string) error {
+	return common.ValidateAddress(address)
+}
+
+// ParsePublicKey parses a P-256 public key. Use common.ParsePublicKeyForAddress for keys of other schemes.
+func ParsePublicKey(s string) (*ecdsa.PublicKey, error) {
+	return common.ParsePublicKey(s)
+}
+
+func (w *Wallet) Scheme() common.SignatureScheme {
+	return w.scheme
+}
+
+func (w *Wallet) PrivateKey() crypto.Signer {
 	return w.privateKey
 }
 
+// PrivateKeyBytes are the 32 bytes of the private key: the scalar for ECDSA and the seed for Ed25519.
+func (w *Wallet) PrivateKeyBytes() []byte {
+	return w.scheme.PrivateKeyBytes(w.privateKey)
+}
+
 func (w *Wallet) PrivateKeyStr() string {
-	return fmt.Sprintf("%x", w.privateKey.D.Bytes())
+	return hex.EncodeToString(w.PrivateKeyBytes())
 }
 
-func (w *Wallet) PublicKey() *ecdsa.PublicKey {
+func (w *Wallet) PublicKey() crypto.PublicKey {
 	return w.publicKey
 }
 
 func (w *Wallet) PublicKeyStr() string {
-	return fmt.Sprintf("%064x%064x", w.publicKey.X, w.publicKey.Y)
+	return w.scheme.EncodePublicKey(w.publicKey)
+}
+
+// CompressedPublicKeyStr is the 33 bytes encoding of an ECDSA key used by standard tooling.
+// It is empty for other schemes.
+func (w *Wallet) CompressedPublicKeyStr() string {
+	k, ok := w.publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return ""
+	}
+	return common.CompressedPublicKeyString(k)
+}
+
+// Curve is the curve of an ECDSA key. It is empty for other schemes.
+func (w *Wallet) Curve() string {
+	return common.CurveOfScheme(w.scheme)
 }
 
 func (w *Wallet) BlockchainAddress() string {
 	return w.blockchainAddress
 }
 
+// Sign signs a digest with the scheme of the wallet and returns the hex of the signature.
+func (w *Wallet) Sign(digest []byte) string {
+	return hex.EncodeToString(w.scheme.Sign(w.privateKey, digest))
+}
+
 func (w *Wallet) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		PrivateKey        string `json:"private_key"`
-		PublicKey         string `json:"public_key"`
-		BlockchainAddress string `json:"blockchain_address"`
+		PrivateKey          string `json:"private_key"`
+		PrivateKeyWIF       string `json:"private_key_wif,omitempty"`
+		PublicKey           string `json:"public_key"`
+		PublicKeyCompressed string `json:"public_key_compressed,omitempty"`
+		Curve               string `json:"curve,omitempty"`
+		SignatureScheme     string `json:"signature_scheme"`
+		BlockchainAddress   string `json:"blockchain_address"`
 	}{
-		PrivateKey:        w.PrivateKeyStr(),
-		PublicKey:         w.PublicKeyStr(),
-		BlockchainAddress: w.blockchainAddress,
+		PrivateKey:          w.PrivateKeyStr(),
+		PrivateKeyWIF:       w.WIF(),
+		PublicKey:           w.PublicKeyStr(),
+		PublicKeyCompressed: w.CompressedPublicKeyStr(),
+		Curve:               w.Curve(),
+		SignatureScheme:     w.scheme.ID(),
+		BlockchainAddress:   w.blockchainAddress,
 	})
 }
 
@@ -102,65 +195,176 @@ func (w *Wallet) MarshalJSON() ([]byte, error) {
 // walletで生成したprivateKey, publicKey, BlockchainAddressなどの情報を使用する
 // https://dev.classmethod.jp/articles/blockchain-basic/
 type Transaction struct {
-	senderPrivateKey           *ecdsa.PrivateKey `json:"-"`
-	senderPublicKey            *ecdsa.PublicKey  `json:"-"`
-	SenderBlockchainAddress    string            `json:"sender_blockchain_address"`
-	RecipientBlockchainAddress string            `json:"recipient_blockchain_address"`
-	Value                      float64           `json:"value"`
+	senderPrivateKey           crypto.Signer               `json:"-"`
+	senderPublicKey            crypto.PublicKey            `json:"-"`
+	SenderBlockchainAddress    string                      `json:"sender_blockchain_address"`
+	RecipientBlockchainAddress string                      `json:"recipient_blockchain_address"`
+	Value                      float64                     `json:"value"`
+	Outputs                    []*common.TransactionOutput `json:"outputs,omitempty"`
+	Type                       string                      `json:"type,omitempty"`
+	Nonce                      uint64                      `json:"nonce"`
+}
+
+// nonce is the next nonce of the sender on the blockchain nodes, see common.TransactionMessage.
+func NewTransaction(privateKey crypto.Signer, publicKey crypto.PublicKey,
+	sender string, recipient string, value float64, nonce uint64) *Transaction {
+	return &Transaction{privateKey, publicKey, sender, recipient, value, nil, "", nonce}
+}
+
+// NewBatchTransaction pays every output with one signature.
+func NewBatchTransaction(privateKey crypto.Signer, publicKey crypto.PublicKey,
+	sender string, outputs []*common.TransactionOutput, nonce uint64) *Transaction {
+	return &Transaction{privateKey, publicKey, sender, "", common.OutputsTotal(outputs), outputs, "", nonce}
 }
 
-func NewTransaction(privateKey *ecdsa.PrivateKey, publicKey *ecdsa.PublicKey,
-	sender string, recipient string, value float64) *Transaction {
-	return &Transaction{privateKey, publicKey, sender, recipient, value}
+// NewStakeTransaction stakes or unstakes value of the sender, depending on typ.
+func NewStakeTransaction(privateKey crypto.Signer, publicKey crypto.PublicKey,
+	sender string, typ string, value float64, nonce uint64) *Transaction {
+	return &Transaction{privateKey, publicKey, sender, "", value, nil, typ, nonce}
 }
 
-func (t *Transaction) GenerateSignature() *common.Signature {
-	m, _ := json.Marshal(t)
-	h := sha256.Sum256(m)
-	r, s, _ := ecdsa.Sign(rand.Reader, t.senderPrivateKey, h[:])
-	return &common.Signature{
-		R: r,
-		S: s,
+// GenerateSignature signs the transaction with the scheme of the key and returns the hex of the signature.
+// Clients that sign locally with the same scheme get exactly the same signature.
+func (t *Transaction) GenerateSignature() string {
+	scheme, err := common.SchemeOfPublicKey(t.senderPublicKey)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(scheme.Sign(t.senderPrivateKey, t.Digest()))
+}
+
+// SignatureScheme is the ID of the scheme of the sender key.
+func (t *Transaction) SignatureScheme() string {
+	scheme, err := common.SchemeOfPublicKey(t.senderPublicKey)
+	if err != nil {
+		return ""
 	}
+	return scheme.ID()
+}
+
+// ID is the transaction ID on the blockchain nodes. It doesn't depend on the signature.
+func (t *Transaction) ID() string {
+	return hex.EncodeToString(t.Digest())
+}
+
+func (t *Transaction) Digest() []byte {
+	if t.Type != "" {
+		return common.NewStakeTransactionMessage(t.SenderBlockchainAddress, t.Type, t.Value, t.Nonce).Digest()
+	}
+	if len(t.Outputs) > 0 {
+		return common.NewBatchTransactionMessage(t.SenderBlockchainAddress, t.Outputs, t.Nonce).Digest()
+	}
+	return common.TransactionDigest(t.SenderBlockchainAddress, t.RecipientBlockchainAddress, t.Value, t.Nonce)
 }
 
 // validater: https://zenn.dev/mattn/articles/893f28eff96129
-type TransactionRequest struct {
-	SenderPrivateKey           string  `json:"sender_private_key"`
+// 署名前のtransaction。秘密鍵はserverに送らない。
+// recipient_contactを指定した場合はaddress bookのアドレスに送る。
+// typeを指定した場合はstakeまたはunstakeになり、recipientは指定しない。
+type PrepareTransactionRequest struct {
 	SenderPublicKey            string  `json:"sender_public_key"`
 	SenderBlockchainAddress    string  `json:"sender_blockchain_address"`
 	RecipientBlockchainAddress string  `json:"recipient_blockchain_address"`
+	RecipientContact           string  `json:"recipient_contact"`
 	Value                      float64 `json:"value"`
+	Type                       string  `json:"type"`
 }
 
-func (t TransactionRequest) Validate() error {
+func (t PrepareTransactionRequest) Validate() error {
 	return validation.ValidateStruct(&t,
-		validation.Field(&t.SenderPrivateKey, validation.Required, validation.Length(64, 64)), // 32 bytes(256bits): 16進数1桁が2進数4桁→4ビットで表現できる。 16進数で64文字,
-		validation.Field(&t.SenderPublicKey, validation.Required, validation.Length(128, 128)),
+		validation.Field(&t.SenderPublicKey, validation.Required, publicKeyRule(t.SenderBlockchainAddress)),
 		validation.Field(&t.SenderBlockchainAddress, validation.Required, validation.Length(26, 35)),
-		validation.Field(&t.RecipientBlockchainAddress, validation.Required, validation.Length(26, 35)),
+		validation.Field(&t.RecipientBlockchainAddress, recipientRules(t.RecipientContact, t.Type)...),
+		validation.Field(&t.RecipientContact, validation.When(t.Type != "", validation.Empty)),
 		validation.Field(&t.Value, validation.Required, validation.Min(1.0)), // TODO: validation効いてない
+		validation.Field(&t.Type, validation.In(common.StakeTransactionTypes...)),
 	)
 }
 
-type BlockchainTransactionRequest struct {
+// recipientRules: a payment needs a recipient or a contact, and stake or unstake has none.
+func recipientRules(contact, typ string) []validation.Rule {
+	return []validation.Rule{
+		validation.Required.When(contact == "" && typ == ""),
+		validation.When(typ != "", validation.Empty),
+		validation.Length(26, 35),
+	}
+}
+
+type PrepareTransactionResponse struct {
+	ID          string                     `json:"id"` // transaction ID once the node accepts it
+	Transaction *common.TransactionMessage `json:"transaction"`
+	Message     string                     `json:"message"` // exact bytes to hash
+	Digest      string                     `json:"digest"`  // hex of sha256(message), the value to sign
+}
+
+// クライアント側で署名済みのtransaction。
+// SenderWalletを指定した場合はkeystoreのunlock済みの鍵でserverが署名する。
+type TransactionRequest struct {
+	SenderWallet               string  `json:"sender_wallet"` // keystore ID or blockchain address
+	SenderPublicKey            string  `json:"sender_public_key"`
 	SenderBlockchainAddress    string  `json:"sender_blockchain_address"`
 	RecipientBlockchainAddress string  `json:"recipient_blockchain_address"`
-	SenderPublicKey            string  `json:"sender_public_key"`
+	RecipientContact           string  `json:"recipient_contact"` // name in the address book
 	Value                      float64 `json:"value"`
 	Signature                  string  `json:"signature"`
+	SignatureScheme            string  `json:"signature_scheme"` // empty means the scheme of the sender address
+	Type                       string  `json:"type"`             // stake or unstake. Empty means a payment
+	Nonce                      uint64  `json:"nonce"`            // nonce of the prepared message. The server asks the node when it signs
+}
+
+func (t TransactionRequest) Validate() error {
+	signedByClient := t.SenderWallet == ""
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.SenderPublicKey, validation.When(signedByClient, validation.Required, publicKeyRule(t.SenderBlockchainAddress))),
+		validation.Field(&t.SenderBlockchainAddress, validation.When(signedByClient, validation.Required, validation.Length(26, 35))),
+		validation.Field(&t.RecipientBlockchainAddress, recipientRules(t.RecipientContact, t.Type)...),
+		validation.Field(&t.RecipientContact, validation.When(t.Type != "", validation.Empty)),
+		validation.Field(&t.Value, validation.Required, validation.Min(1.0)), // TODO: validation効いてない
+		validation.Field(&t.Signature, validation.When(signedByClient, validation.Required, signatureRule(t.SignatureScheme, t.SenderBlockchainAddress))),
+		validation.Field(&t.SignatureScheme, validation.When(signedByClient, signatureSchemeRule(t.SenderBlockchainAddress))),
+		validation.Field(&t.Type, validation.In(common.StakeTransactionTypes...)),
+	)
+}
+
+// Outputsを指定した場合はbatch transactionになる。
+type BlockchainTransactionRequest struct {
+	SenderBlockchainAddress    string                      `json:"sender_blockchain_address"`
+	RecipientBlockchainAddress string                      `json:"recipient_blockchain_address"`
+	SenderPublicKey            string                      `json:"sender_public_key"`
+	Value                      float64                     `json:"value"`
+	Outputs                    []*common.TransactionOutput `json:"outputs,omitempty"`
+	Signature                  string                      `json:"signature"`
+	SignatureScheme            string                      `json:"signature_scheme"`
+	Type                       string                      `json:"type,omitempty"`
+	Nonce                      uint64                      `json:"nonce"`
 }
 
 func (t BlockchainTransactionRequest) Validate() error {
 	return validation.ValidateStruct(&t,
 		validation.Field(&t.SenderBlockchainAddress, validation.Required, validation.Length(26, 35)),
-		validation.Field(&t.RecipientBlockchainAddress, validation.Required, validation.Length(26, 35)),
-		validation.Field(&t.SenderPublicKey, validation.Required, validation.Length(128, 128)),
+		validation.Field(&t.RecipientBlockchainAddress, validation.Required.When(len(t.Outputs) == 0 && t.Type == ""), validation.Length(26, 35)),
+		validation.Field(&t.SenderPublicKey, validation.Required, publicKeyRule(t.SenderBlockchainAddress)),
 		validation.Field(&t.Value, validation.Required),
-		validation.Field(&t.Signature, validation.Required),
+		validation.Field(&t.Signature, validation.Required, signatureRule(t.SignatureScheme, t.SenderBlockchainAddress)),
+		validation.Field(&t.SignatureScheme, signatureSchemeRule(t.SenderBlockchainAddress)),
+		validation.Field(&t.Type, validation.In(common.StakeTransactionTypes...)),
 	)
 }
 
-type AmountResponse struct {
-	Amount float64 `json:"amount"`
+type NodeHealth struct {
+	URL       string `json:"url"`
+	Reachable bool   `json:"reachable"`
+	Network   string `json:"network"` // network the node reports
+}
+
+type HealthResponse struct {
+	Status  string       `json:"status"`
+	Network string       `json:"network"` // network of the wallet
+	Nodes   []NodeHealth `json:"nodes"`
+}
+
+// NonceResponse is the nonce of the next transaction of an address on the node.
+type NonceResponse struct {
+	BlockchainAddress string `json:"blockchain_address"`
+	Nonce             uint64 `json:"nonce"`
 }
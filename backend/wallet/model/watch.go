@@ -0,0 +1,62 @@
+package model
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/yagikota/blockchain_with_go/backend/common"
+)
+
+// watch-only walletの登録。blockchain_addressかpublic_keyのどちらかを指定する。
+// public_keyだけの場合、signature_schemeかcurveで鍵の種類を指定する (省略時はecdsa-p256)。
+type WatchRequest struct {
+	Label             string `json:"label"`
+	BlockchainAddress string `json:"blockchain_address"`
+	PublicKey         string `json:"public_key"`
+	Curve             string `json:"curve"`
+	Scheme            string `json:"signature_scheme"`
+}
+
+func (r WatchRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Label, validation.Required, validation.Length(1, 100)),
+		validation.Field(&r.BlockchainAddress, validation.Required.When(r.PublicKey == ""), validation.Length(26, 35)),
+		validation.Field(&r.Curve, validation.In(common.Curves...)),
+		validation.Field(&r.Scheme, validation.In(common.SignatureSchemes...)),
+	)
+}
+
+type WatchBalance struct {
+	Label             string  `json:"label"`
+	BlockchainAddress string  `json:"blockchain_address"`
+	Amount            float64 `json:"amount"`
+}
+
+type WatchBalancesResponse struct {
+	Wallets     []WatchBalance `json:"wallets"`
+	TotalAmount float64        `json:"total_amount"`
+}
+
+// directions of a transaction seen from the watched set.
+const (
+	DIRECTION_IN       = "in"
+	DIRECTION_OUT      = "out"
+	DIRECTION_INTERNAL = "internal" // between two watched addresses
+)
+
+type WatchHistoryEntry struct {
+	BlockHeight                int     `json:"block_height"`
+	Timestamp                  int64   `json:"timestamp"`
+	SenderBlockchainAddress    string  `json:"sender_blockchain_address"`
+	SenderLabel                string  `json:"sender_label,omitempty"`
+	RecipientBlockchainAddress string  `json:"recipient_blockchain_address"`
+	RecipientLabel             string  `json:"recipient_label,omitempty"`
+	Value                      float64 `json:"value"`
+	Direction                  string  `json:"direction"`
+	// Outputs lists the recipients of a batch transaction, which is one entry of the history.
+	Outputs []*common.TransactionOutput `json:"outputs,omitempty"`
+}
+
+type WatchHistoryResponse struct {
+	History  []WatchHistoryEntry `json:"history"`
+	Received float64             `json:"received"` // from outside the watched set
+	Sent     float64             `json:"sent"`     // to outside the watched set
+}
@@ -0,0 +1,140 @@
+// Package watchonly keeps wallets that have no private key. They can be used to
+// monitor balances and history of addresses, but never to sign a transaction.
+package watchonly
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/yagikota/blockchain_with_go/backend/common"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/filestore"
+	"github.com/yagikota/blockchain_with_go/backend/wallet/model"
+)
+
+var (
+	ErrNotFound       = errors.New("watch-only wallet not found")
+	ErrAlreadyWatched = errors.New("address is already watched")
+	ErrKeyMismatch    = errors.New("public_key does not match blockchain_address")
+	ErrNoAddress      = errors.New("blockchain_address or public_key is required")
+)
+
+// Wallet is a watch-only wallet. The address is the ID of the wallet.
+type Wallet struct {
+	BlockchainAddress string    `json:"blockchain_address"`
+	PublicKey         string    `json:"public_key,omitempty"`
+	Label             string    `json:"label"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Store keeps all watch-only wallets in one JSON file.
+type Store struct {
+	path string
+
+	mux     sync.Mutex
+	wallets []*Wallet
+}
+
+func New(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := filestore.ReadJSON(path, &s.wallets); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add watches an address. When a public key is given, the address is derived from it
+// and, if an address is given too, both must agree. The scheme of the key is the one of
+// the address, or scheme when only the key is given (ECDSA P-256 when nil).
+func (s *Store) Add(label, address, publicKey string, scheme common.SignatureScheme) (*Wallet, error) {
+	if publicKey != "" {
+		var err error
+		if address != "" {
+			scheme, err = common.SchemeOfAddress(address)
+		} else if scheme == nil {
+			scheme, err = common.SchemeByID(common.SCHEME_ECDSA_P256)
+		}
+		if err != nil {
+			return nil, err
+		}
+		pub, err := scheme.ParsePublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		derived := scheme.Address(pub)
+		if address != "" && address != derived {
+			return nil, ErrKeyMismatch
+		}
+		address = derived
+	}
+	if address == "" {
+		return nil, ErrNoAddress
+	}
+	if err := model.ValidateAddress(address); err != nil {
+		return nil, err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.find(address) != nil {
+		return nil, ErrAlreadyWatched
+	}
+	w := &Wallet{
+		BlockchainAddress: address,
+		PublicKey:         publicKey,
+		Label:             label,
+		CreatedAt:         time.Now().UTC(),
+	}
+	if err := s.save(append(s.wallets, w)); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *Store) List() []*Wallet {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]*Wallet{}, s.wallets...)
+}
+
+func (s *Store) Find(address string) (*Wallet, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if w := s.find(address); w != nil {
+		return w, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *Store) find(address string) *Wallet {
+	for _, w := range s.wallets {
+		if w.BlockchainAddress == address {
+			return w
+		}
+	}
+	return nil
+}
+
+func (s *Store) Delete(address string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	wallets := make([]*Wallet, 0, len(s.wallets))
+	for _, w := range s.wallets {
+		if w.BlockchainAddress != address {
+			wallets = append(wallets, w)
+		}
+	}
+	if len(wallets) == len(s.wallets) {
+		return ErrNotFound
+	}
+	return s.save(wallets)
+}
+
+// save writes the file first, so that memory and disk never disagree.
+func (s *Store) save(wallets []*Wallet) error {
+	if err := filestore.WriteJSON(s.path, wallets); err != nil {
+		return err
+	}
+	s.wallets = wallets
+	return nil
+}